@@ -1,10 +1,15 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/fristonio/gvm/gvm"
 	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/network"
+	"github.com/fristonio/gvm/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -13,29 +18,203 @@ A Fast and Flexible version manager for Golang built with
 love by fristonio in Go.
 Complete source code is available at https://github.com/fristonio/gvm`
 
-var log *logger.Logger = logger.New(os.Stdout)
+var log *logger.Logger = logger.Log
+
+var (
+	// Skip the verification of TLS certificates for all the requests
+	insecure bool
+	// Extra CA bundle to trust for TLS connections
+	caBundle string
+	// Log debug messages
+	verbose bool
+	// Only log warnings and errors, also hides the output of compilations
+	quiet bool
+	// Minimum level of the logged messages
+	logLevel string
+	// Coloring of the logged messages, one of auto, always or never
+	colorMode string
+	// Never color the logged messages, same as --color=never
+	noColor bool
+	// Format of the logged messages, one of text or json
+	logFormat string
+	// Set once cobra parsed the arguments and the command started running
+	commandStarted bool
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "gvm",
 	Short: "gvm is a fast and reliable version manager for go",
 	Long:  longDescriptionGvm,
-	Run: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandStarted = true
+		// Errors of the flags are logged with the logger configured as per the others
+		colorErr := manageColor()
+		formatErr := manageLogFormat()
+		levelErr := manageLogLevel(cmd)
+		for _, err := range []error{colorErr, formatErr, levelErr} {
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := manageConfig(cmd); err != nil {
+			return err
+		}
+
+		if caBundle == "" {
+			caBundle = os.Getenv("GVM_CA_BUNDLE")
+		}
+		if caBundle != "" {
+			if err := network.SetCABundle(caBundle); err != nil {
+				return utils.WrapError(utils.USAGE_ERROR, err, "Invalid CA bundle")
+			}
+		}
+		startOperation(cmd)
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		log.Warn("No arguments are supplied ... ")
-		cmd.Help()
+		return cmd.Help()
 	},
+
+	// Errors are logged by Execute along with the exit code for them
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
+// Run the command for the arguments of gvm and exit, with the exit code for the
+// kind of the error returned by the command if it failed.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	cmd, err := rootCmd.ExecuteC()
+	if _, ok := err.(*utils.Error); !ok && !commandStarted {
+		// The command did not run, cobra refused an unknown command or its arguments
+		err = utils.WrapError(utils.USAGE_ERROR, err, "Invalid arguments")
+	}
+	code := exitCode(err)
+	finishOperation(code)
+
+	if err != nil {
+		log.Error(err)
+		if utils.KindOf(err) == utils.USAGE_ERROR {
+			log.Infof("Run '%s --help' for usage.", cmd.CommandPath())
+		}
+	}
+	os.Exit(code)
+}
+
+// Returns the manager of the go versions under gvm root, with a client configured
+// as per the TLS flags.
+func newManager() *gvm.Manager {
+	if insecure {
+		log.Warn("TLS certificate verification is disabled")
+	}
+	return gvm.NewManager(gvm.Config{
+		Root:   utils.GVM_ROOT_DIR,
+		Log:    log,
+		Client: network.NewClient(insecure),
+		Pkgset: gvmConfig.Pkgset,
+	})
+}
+
+// Returns a context which is cancelled on the first interrupt (Ctrl-C) or termination
+// signal, so that the running operation stops and cleans up after itself. The
+// returned function releases the signal handler.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	go func() {
+		select {
+		case <-signals:
+			log.Warn("Interrupted, cleaning things up ...")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+// Set the level of the logger as per the verbosity flags, which can not be combined
+func manageLogLevel(cmd *cobra.Command) error {
+	level, err := logger.ParseLevel(logLevel)
+	if err != nil {
+		return utils.WrapError(utils.USAGE_ERROR, err, "Invalid --log-level")
+	}
+
+	changed := 0
+	for _, flag := range []string{"verbose", "quiet", "log-level"} {
+		if cmd.Flags().Changed(flag) {
+			changed++
+		}
+	}
+	if changed > 1 {
+		return utils.NewError(utils.USAGE_ERROR, "Only one of --verbose, --quiet and --log-level can be provided")
 	}
+
+	if verbose {
+		level = logger.DebugLevel
+	} else if quiet {
+		level = logger.WarnLevel
+	}
+	log.SetLevel(level)
+	return nil
+}
+
+// Set the formatter of the logger as per the log format flag
+func manageLogFormat() error {
+	formatter, err := logger.NewFormatter(logFormat)
+	if err != nil {
+		return utils.WrapError(utils.USAGE_ERROR, err, "Invalid --log-format")
+	}
+	log.SetFormatter(formatter)
+	return nil
+}
+
+// Set the coloring of the logger as per the color flags
+func manageColor() error {
+	if noColor {
+		colorMode = logger.ColorNever
+	}
+	if err := log.SetColorMode(colorMode); err != nil {
+		log.SetColor(false)
+		return utils.WrapError(utils.USAGE_ERROR, err, "Invalid --color")
+	}
+	return nil
 }
 
 func init() {
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return utils.WrapError(utils.USAGE_ERROR, err, "Invalid flags")
+	})
+
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false,
+		"Log debug messages")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false,
+		"Only log warnings and errors, showing a spinner instead of the output of compilations")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info",
+		"Minimum level of the logged messages, one of debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logger.TextFormat,
+		"Format of the logged messages, text or json with one object per line")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", logger.ColorAuto,
+		"Color the logged messages, one of auto, always or never")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false,
+		"Never color the logged messages, same as --color=never")
+	rootCmd.PersistentFlags().BoolVar(&insecure, "insecure", false,
+		"Skip the verification of TLS certificates")
+	rootCmd.PersistentFlags().StringVar(&caBundle, "ca-bundle", "",
+		"PEM encoded bundle of extra CA certificates to trust, defaults to $GVM_CA_BUNDLE")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "",
+		"Configuration file with the defaults of gvm (default ~/.gvm/config.toml)")
+
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(listRemoteCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(installCmd)
 	rootCmd.AddCommand(uninstallCmd)
+	rootCmd.AddCommand(useCmd)
+	rootCmd.AddCommand(selftestCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(configCmd)
 }
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fristonio/gvm/config"
+	"github.com/fristonio/gvm/utils"
+	"github.com/spf13/cobra"
+)
+
+// Path of the configuration file, defaults to config.toml in gvm root
+var configPath string
+
+// Configuration loaded from the configuration file before running a command
+var gvmConfig = config.Default()
+
+// View and edit the configuration file
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit the defaults of gvm in the configuration file",
+	Long: `Views and edits the defaults of gvm kept in the configuration file ~/.gvm/config.toml,
+or the one provided with --config. Flags and environment variables take precedence over them.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+// Print the value of a key of the configuration
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a key of the configuration",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return utils.NewError(utils.USAGE_ERROR, "Use format : gvm config get <key>")
+		}
+		value, err := gvmConfig.Get(args[0])
+		if err != nil {
+			return utils.WrapError(utils.USAGE_ERROR, err, "Invalid key")
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// Set the value of a key of the configuration
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set the value of a key of the configuration",
+	Long: `Sets the value of a key of the configuration and saves it to the configuration file,
+lists like the mirrors are separated with commas.
+    gvm config set install_mode binary
+    gvm config set mirrors /mnt/go-archives,https://mirror.example.com/go`,
+
+	Annotations: recordedCommand,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return utils.NewError(utils.USAGE_ERROR, "Use format : gvm config set <key> <value>")
+		}
+		if err := gvmConfig.Set(args[0], args[1]); err != nil {
+			return utils.WrapError(utils.USAGE_ERROR, err, "Invalid configuration")
+		}
+		if err := gvmConfig.Save(manageConfigPath()); err != nil {
+			return utils.WrapError(utils.GENERIC_ERROR, err, "Error while saving the configuration")
+		}
+		return nil
+	},
+}
+
+// List the keys of the configuration with their values
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys of the configuration with their values",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, key := range config.Keys() {
+			value, _ := gvmConfig.Get(key)
+			fmt.Printf("%s = %s\n", key, value)
+		}
+		return nil
+	},
+}
+
+// Returns the path of the configuration file as per the --config flag
+func manageConfigPath() string {
+	if configPath == "" {
+		return config.DefaultPath()
+	}
+	return configPath
+}
+
+// Load the configuration file for the command, a configuration file provided with
+// --config must exist unless it is created by config set. The config commands load
+// a configuration with invalid values leniently, reporting the error, so that it
+// can be viewed and fixed.
+func manageConfig(cmd *cobra.Command) error {
+	path := manageConfigPath()
+	if configPath != "" && cmd != configSetCmd {
+		if _, err := os.Stat(path); err != nil {
+			return utils.WrapError(utils.NOT_FOUND_ERROR, err, "Invalid --config")
+		}
+	}
+
+	var c *config.Config
+	var err error
+	if cmd == configCmd || cmd.Parent() == configCmd {
+		if c, err = config.LoadLenient(path); c != nil && err != nil {
+			log.Warnf("%v", err)
+			err = nil
+		}
+	} else {
+		c, err = config.Load(path)
+	}
+	if err != nil {
+		return utils.WrapError(utils.USAGE_ERROR, err, "Error while loading the configuration")
+	}
+	gvmConfig = c
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/fristonio/gvm/utils"
+)
+
+// Exit codes of gvm for each kind of error
+const (
+	EXIT_SUCCESS   = 0
+	EXIT_FAILURE   = 1
+	EXIT_USAGE     = 2
+	EXIT_NOT_FOUND = 3
+	EXIT_NETWORK   = 4
+	EXIT_CHECKSUM  = 5
+	EXIT_BUILD     = 6
+	EXIT_ABORTED   = 130
+)
+
+var exitCodes = map[utils.ErrorKind]int{
+	utils.GENERIC_ERROR:   EXIT_FAILURE,
+	utils.USAGE_ERROR:     EXIT_USAGE,
+	utils.NOT_FOUND_ERROR: EXIT_NOT_FOUND,
+	utils.NETWORK_ERROR:   EXIT_NETWORK,
+	utils.CHECKSUM_ERROR:  EXIT_CHECKSUM,
+	utils.BUILD_ERROR:     EXIT_BUILD,
+	utils.ABORTED_ERROR:   EXIT_ABORTED,
+}
+
+// Returns the exit code for the error returned by a command
+func exitCode(err error) int {
+	if err == nil {
+		return EXIT_SUCCESS
+	}
+	return exitCodes[utils.KindOf(err)]
+}
+
+// Returns the usage error for a command run without the version it expects,
+// listing the examples of its usage.
+func noVersionError(usage string) error {
+	return utils.NewError(utils.USAGE_ERROR, "No version for go is provided\nUse format : %s", usage)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{err: nil, code: EXIT_SUCCESS},
+		{err: errors.New("failed"), code: EXIT_FAILURE},
+		{err: utils.NewError(utils.GENERIC_ERROR, "failed"), code: EXIT_FAILURE},
+		{err: utils.NewError(utils.USAGE_ERROR, "failed"), code: EXIT_USAGE},
+		{err: utils.NewError(utils.NOT_FOUND_ERROR, "failed"), code: EXIT_NOT_FOUND},
+		{err: utils.NewError(utils.NETWORK_ERROR, "failed"), code: EXIT_NETWORK},
+		{err: utils.NewError(utils.CHECKSUM_ERROR, "failed"), code: EXIT_CHECKSUM},
+		{err: utils.NewError(utils.BUILD_ERROR, "failed"), code: EXIT_BUILD},
+		{err: utils.NewError(utils.ABORTED_ERROR, "failed"), code: EXIT_ABORTED},
+		// A wrapped error keeps the kind it already has
+		{err: utils.WrapError(utils.USAGE_ERROR, utils.NewError(utils.NETWORK_ERROR, "failed"), "wrapped"), code: EXIT_NETWORK},
+		{err: utils.WrapError(utils.BUILD_ERROR, errors.New("failed"), "wrapped"), code: EXIT_BUILD},
+	}
+	for _, test := range tests {
+		if code := exitCode(test.err); code != test.code {
+			t.Errorf("exitCode(%v) = %d, expected %d", test.err, code, test.code)
+		}
+	}
+}
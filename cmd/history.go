@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fristonio/gvm/manager"
+	"github.com/fristonio/gvm/utils"
+	"github.com/spf13/cobra"
+)
+
+// Annotation marking the commands recorded in the operation log
+const historyAnnotation = "gvm:history"
+
+// Annotations of the commands which change the installations
+var recordedCommand = map[string]string{historyAnnotation: "true"}
+
+// Operation of the command being run, nil if it is not recorded
+var currentOperation *manager.Operation
+
+// Number of operations shown, all of them when 0
+var historyLimit int
+
+// List the operations recorded in the operation log
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the past operations which changed the installations",
+	Long: `Lists the past install, uninstall, use, selftest and config set operations with their arguments,
+duration and outcome, as recorded in the operation log ~/.gvm/logs/gvm.log`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		operations, err := manager.ReadHistory(utils.DefaultPaths())
+		if err != nil {
+			return utils.WrapError(utils.GENERIC_ERROR, err, "Error while reading the operation log")
+		}
+		if historyLimit > 0 && len(operations) > historyLimit {
+			operations = operations[len(operations)-historyLimit:]
+		}
+
+		for _, op := range operations {
+			outcome := op.Outcome
+			if op.Outcome == manager.OPERATION_FAILED {
+				outcome = fmt.Sprintf("%s (exit code %d)", op.Outcome, op.ExitCode)
+			}
+			fmt.Printf("%s  %-8s  %-28s  gvm %s\n", op.Start.Local().Format("2006-01-02 15:04:05"),
+				op.Duration, outcome, strings.Join(op.Args, " "))
+		}
+		return nil
+	},
+}
+
+// Start recording the operation if the command changes the installations
+func startOperation(cmd *cobra.Command) {
+	if cmd.Annotations[historyAnnotation] == "" {
+		return
+	}
+	currentOperation = &manager.Operation{
+		Start:   time.Now(),
+		Command: cmd.Name(),
+		Args:    os.Args[1:],
+	}
+}
+
+// Record the operation being run, if any, with the exit code of the command
+func finishOperation(code int) {
+	op := currentOperation
+	if op == nil {
+		return
+	}
+	currentOperation = nil
+
+	op.Duration = time.Since(op.Start).Round(time.Millisecond).String()
+	op.ExitCode = code
+	op.Outcome = manager.OPERATION_SUCCEEDED
+	if code != 0 {
+		op.Outcome = manager.OPERATION_FAILED
+	}
+	if err := manager.RecordOperation(utils.DefaultPaths(), *op); err != nil {
+		log.Warnf("Could not record the operation : %v", err)
+	}
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20,
+		"Number of most recent operations listed, 0 to list all of them")
+}
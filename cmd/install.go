@@ -4,14 +4,52 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
 
+	"github.com/fristonio/gvm/config"
+	"github.com/fristonio/gvm/extract"
+	"github.com/fristonio/gvm/gvm"
 	"github.com/fristonio/gvm/manager"
 	"github.com/fristonio/gvm/network"
 	"github.com/fristonio/gvm/utils"
 	"github.com/spf13/cobra"
 )
 
+// Patch files to apply to the source tree before compilation
+var installPatches []string
+
+// Options for the compilation of go source
+var installBuildOpts manager.BuildOptions
+
+// Run the go test suite after the compilation
+var installRunTests bool
+
+// Number of times a failing part of the download is retried
+var installRetries int
+
+// Maximum download rate like 2M, defaults to $GVM_LIMIT_RATE
+var installLimitRate string
+
+// Location of a mirror of the release archives, defaults to $GVM_MIRROR
+var installMirror string
+
+// Install the official binary distribution instead of compiling from source
+var installBinary bool
+
+// Compile from source even if the configured install mode is binary
+var installSource bool
+
+// Maximum total size of the extracted archive like 4G
+var installMaxExtractSize string
+
+// Maximum number of entries extracted from the archive
+var installMaxExtractFiles int
+
+// Stream the archive into the installation without keeping it in the downloads directory
+var installNoCache bool
+
+// Flags of the build options for the compilation
+var buildFlags = []string{"cgo-enabled", "goexperiment", "gcflags", "ldflags", "microarch", "no-clean"}
+
 var installCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Installs the version of go mentioned against this flag",
@@ -19,66 +57,94 @@ var installCmd = &cobra.Command{
 For this it first calls the downloader to download the zip for the version of go
 Then install build it to be used`,
 
-	Run: func(cmd *cobra.Command, args []string) {
+	Annotations: recordedCommand,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			utils.Log.Error("No version for go is provided")
-			utils.Log.Error(`Use format : gvm install [go version]
+			return noVersionError(`gvm install [go version]
     gvm install go1.9
+    gvm install go1.9@variant
     To list version available for use : gvm list-remote`)
-			os.Exit(1)
 		}
 
-		releaseName := args[0]
-		if utils.GOS_REGEXP.FindString(releaseName) != "" {
-			// Once we got go version from the user, check if it already exist in downloads
-			// If it does check if it is installed
-			// Prompt user to fix it if it is already installed
-			// Otherwise download the version source from remote, copy it to Gos directory
-			// Build it, create an environment file for it.
-			releases, err := network.ParseGoReleases(false)
-			if err != nil {
-				utils.Log.Errorf("An error occured while parsing available releases : %v", err)
-				os.Exit(1)
+		opts, err := manageInstallOptions(cmd)
+		if err != nil {
+			return err
+		}
+		if opts.Binary {
+			if err := manageBinaryFlags(cmd); err != nil {
+				return err
 			}
+		}
+		ctx, cancel := interruptContext()
+		defer cancel()
+		_, err = newManager().Install(ctx, args[0], opts)
+		return err
+	},
+}
 
-			var goRelease network.Release
-			var flag bool
-
-			for _, release := range releases {
-				if release.Name == releaseName {
-					goRelease = release
-					flag = true
-					break
-				}
-			}
+// Returns the options of the install as per the flags, the environment and the
+// configuration file, in this order of precedence.
+func manageInstallOptions(cmd *cobra.Command) (gvm.InstallOptions, error) {
+	if installBinary && installSource {
+		return gvm.InstallOptions{}, utils.NewError(utils.USAGE_ERROR, "Only one of --binary and --source can be provided")
+	}
+	mirror := installMirror
+	if mirror == "" {
+		mirror = os.Getenv("GVM_MIRROR")
+	}
+	mirrors := gvmConfig.Mirrors
+	if mirror != "" {
+		mirrors = []string{mirror}
+	}
+	limitRate, err := manageLimitRate()
+	if err != nil {
+		return gvm.InstallOptions{}, err
+	}
+	maxSize, err := utils.ParseByteSize(installMaxExtractSize)
+	if err != nil {
+		return gvm.InstallOptions{}, utils.WrapError(utils.USAGE_ERROR, err, "Invalid maximum extraction size")
+	}
 
-			if !flag {
-				utils.Log.Errorf(`Could not find a matching go version source.
-	Use gvm list-remote to list all the available versions.`)
-				os.Exit(1)
-			}
+	binary := installBinary
+	if !installSource && gvmConfig.InstallMode == manager.BINARY_INSTALL {
+		binary = true
+	}
+	noCache := installNoCache || gvmConfig.Cache == config.CACHE_NONE
 
-			manageReleaseDownload(goRelease)
-			manageCompressedDownload(goRelease)
+	return gvm.InstallOptions{
+		Binary:          binary,
+		Mirrors:         mirrors,
+		Patches:         installPatches,
+		Build:           manageBuildOptions(cmd),
+		DefaultBuild:    gvmConfig.Build,
+		RunTests:        installRunTests,
+		Connections:     gvmConfig.Connections,
+		Retries:         installRetries,
+		LimitRate:       limitRate,
+		NoCache:         noCache,
+		RemoveArchive:   gvmConfig.Cache == config.CACHE_CLEAN,
+		MaxExtractSize:  maxSize,
+		MaxExtractFiles: installMaxExtractFiles,
+		Quiet:           quiet,
+		ClearDownload:   forceNewDownload,
+	}, nil
+}
 
-			// Compile the source of go obtained
-			utils.Log.Info("Compiling go from source")
-			err = manager.CompileGoRelease(goRelease.Name)
-			if err != nil {
-				utils.Log.Errorf("Error during compilation : %v", err)
-			}
-			manager.CreateEnvironmentFile(goRelease.Name)
-			os.Exit(0)
-		} else {
-			utils.Log.Error("Not a valid go version, it should be of the format : goX.X")
-			os.Exit(1)
+// Check that no flag which only applies to source installs is used with --binary
+func manageBinaryFlags(cmd *cobra.Command) error {
+	for _, flag := range append(buildFlags, "patch") {
+		if cmd.Flags().Changed(flag) {
+			return utils.NewError(utils.USAGE_ERROR, "--%s can not be used for binary installs", flag)
 		}
-	},
+	}
+	return nil
 }
 
-func forceNewDownload() bool {
+// Ask whether the previous download of the archive is to be cleared
+func forceNewDownload(archive string) bool {
 	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Print("[*] Already file exist in .gvm/downloads, force download clearing previous files[Y/N] : ")
+	fmt.Fprintf(os.Stderr, "[*] %s already exists in .gvm/downloads, force download clearing previous files[Y/N] : ", archive)
 	scanner.Scan()
 	text := scanner.Text()
 	if text == "Y" || text == "y" || text == "" {
@@ -87,45 +153,70 @@ func forceNewDownload() bool {
 	return false
 }
 
-func manageReleaseDownload(goRelease network.Release) {
-	downloadPath := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_DOWNLOAD_DIR, filepath.Base(goRelease.DownloadUrl))
-	if !utils.CheckIfAlreadyExist(downloadPath) {
-		utils.Log.Infof("Beggining to download source for %s", goRelease.Name)
-		if err := network.Download(goRelease.DownloadUrl, true, 4, false); err != nil {
-			if forceNewDownload() {
-				if e := network.Download(goRelease.DownloadUrl, true, 4, true); e != nil {
-					utils.Log.Error("An error occured while downloading go from source")
-					os.Exit(1)
-				}
-			} else {
-				utils.Log.Error("Error while downloading go version source")
-				os.Exit(1)
-			}
-		}
-		utils.Log.Info("Download completed...")
-	} else {
-		utils.Log.Infof("Found a cached copy for %s", goRelease.Name)
+// Returns the download rate limit in bytes per second as per the flag, the
+// environment and the configuration file, 0 when there is no limit
+func manageLimitRate() (int64, error) {
+	limitRate := installLimitRate
+	if limitRate == "" {
+		limitRate = os.Getenv("GVM_LIMIT_RATE")
+	}
+	if limitRate == "" {
+		limitRate = gvmConfig.LimitRate
+	}
+	if limitRate == "" {
+		return 0, nil
 	}
+
+	rate, err := utils.ParseByteSize(limitRate)
+	if err != nil {
+		return 0, utils.WrapError(utils.USAGE_ERROR, err, "Invalid download rate limit")
+	}
+	return rate, nil
 }
 
-func manageCompressedDownload(goRelease network.Release) {
-	utils.Log.Info("Unzipping the downloaded source ...")
-	source := filepath.Join(
-		utils.GVM_ROOT_DIR,
-		utils.GVM_DOWNLOAD_DIR,
-		filepath.Base(goRelease.DownloadUrl),
-	)
-	destination := filepath.Join(
-		utils.GVM_ROOT_DIR,
-		utils.GVM_GOS_DIRNAME,
-		goRelease.Name,
-	)
-
-	if utils.CheckIfAlreadyExist(source) {
-		err := utils.UntarToDestination(source, destination)
-		if err != nil {
-			utils.Log.Infof("Error while trying to decompress source : %v", err)
-			os.Exit(1)
+// Returns the build options provided by the flags, when none of the build flags
+// is provided the options are zero so that the ones recorded for a previous
+// install of the version are reused.
+func manageBuildOptions(cmd *cobra.Command) manager.BuildOptions {
+	for _, flag := range buildFlags {
+		if cmd.Flags().Changed(flag) {
+			return installBuildOpts
 		}
 	}
+	return manager.BuildOptions{}
+}
+
+func init() {
+	installCmd.Flags().StringArrayVar(&installPatches, "patch", nil,
+		"Patch file to apply to the source before compilation, can be repeated")
+	installCmd.Flags().StringVar(&installBuildOpts.CgoEnabled, "cgo-enabled", "",
+		"Value of CGO_ENABLED (0 or 1) for the compilation")
+	installCmd.Flags().StringVar(&installBuildOpts.Experiment, "goexperiment", "",
+		"Value of GOEXPERIMENT for the compilation")
+	installCmd.Flags().StringVar(&installBuildOpts.GcFlags, "gcflags", "",
+		"Value of GO_GCFLAGS passed to the compiler while building the toolchain")
+	installCmd.Flags().StringVar(&installBuildOpts.LdFlags, "ldflags", "",
+		"Value of GO_LDFLAGS passed to the linker while building the toolchain")
+	installCmd.Flags().StringVar(&installBuildOpts.MicroArch, "microarch", "",
+		"Microarchitecture level for the host architecture, e.g. v3 for GOAMD64")
+	installCmd.Flags().BoolVar(&installBuildOpts.NoClean, "no-clean", false,
+		"Run make.bash with --no-clean")
+	installCmd.Flags().BoolVar(&installRunTests, "test", false,
+		"Run the go test suite after compilation and record its result")
+	installCmd.Flags().IntVar(&installRetries, "retries", network.DEFAULT_RETRIES,
+		"Number of times a failing part of the download is retried")
+	installCmd.Flags().StringVar(&installLimitRate, "limit-rate", "",
+		"Maximum download rate across all connections like 500K or 2M, defaults to $GVM_LIMIT_RATE")
+	installCmd.Flags().StringVar(&installMirror, "mirror", "",
+		"Mirror of the release archives as an http(s):// or file:// url or a directory, defaults to $GVM_MIRROR or the configured mirrors")
+	installCmd.Flags().BoolVar(&installBinary, "binary", false,
+		"Install the official binary distribution instead of compiling from source")
+	installCmd.Flags().BoolVar(&installSource, "source", false,
+		"Compile from source even if the configured install_mode is binary")
+	installCmd.Flags().StringVar(&installMaxExtractSize, "max-extract-size", "4G",
+		"Maximum total size of the files extracted from the archive, 0 for no limit")
+	installCmd.Flags().IntVar(&installMaxExtractFiles, "max-extract-files", extract.DEFAULT_MAX_FILES,
+		"Maximum number of entries extracted from the archive, 0 for no limit")
+	installCmd.Flags().BoolVar(&installNoCache, "no-cache", false,
+		"Stream the archive over a single connection into the installation without keeping it in the downloads directory")
 }
@@ -1,22 +1,112 @@
 package cmd
 
 import (
+	"fmt"
+
+	"github.com/fristonio/gvm/gvm"
 	"github.com/fristonio/gvm/network"
+	"github.com/fristonio/gvm/utils"
 	"github.com/spf13/cobra"
 )
 
+// Format of the listing, json, yaml or table
+var listRemoteOutput string
+
+// Filters for the listed releases
+var listRemoteFilter network.ReleaseFilter
+
+// Release available to install as listed with --output json or yaml
+type remoteEntry struct {
+	Name string `json:"name" yaml:"name"`
+	// Stability of the release, stable or prerelease
+	Status    string `json:"status" yaml:"status"`
+	Installed bool   `json:"installed" yaml:"installed"`
+	Cached    bool   `json:"cached" yaml:"cached"`
+	Url       string `json:"url" yaml:"url"`
+}
+
 // Print the version of gvm running and exit gracefully
 // Version information are retrived from the version/version.go which is populated at
 // build time using ldflags
 var listRemoteCmd = &cobra.Command{
 	Use:   "list-remote",
 	Short: "List remote version of go available",
-	Long:  `List all the releases of golang that are available`,
+	Long: `List all the releases of golang that are available sorted by version, the releases
+can be filtered with the flags, only the ones matching all of them are listed.
+    gvm list-remote --stable --since go1.18 --latest-per-minor`,
 
-	Run: func(cmd *cobra.Command, args []string) {
-		_, err := network.ParseGoReleases(true)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(listRemoteOutput); err != nil {
+			return err
+		}
+		if err := listRemoteFilter.Validate(); err != nil {
+			return utils.WrapError(utils.USAGE_ERROR, err, "Invalid filters")
+		}
+		ctx, cancel := interruptContext()
+		defer cancel()
+		m := newManager()
+		releases, err := m.ListRemote(ctx)
+		if err != nil {
+			return err
+		}
+		releases, err = network.FilterReleases(releases, listRemoteFilter)
 		if err != nil {
-			log.Errorf("An error occured while parsing available releases : %v", err)
+			return utils.WrapError(utils.USAGE_ERROR, err, "Invalid filters")
 		}
+
+		switch listRemoteOutput {
+		case OUTPUT_JSON, OUTPUT_YAML, OUTPUT_TABLE:
+			entries := newRemoteEntries(releases, m.ReleaseStatus(releases))
+			if listRemoteOutput != OUTPUT_TABLE {
+				return printStructured(listRemoteOutput, entries)
+			}
+			rows := make([][]string, 0, len(entries))
+			for _, e := range entries {
+				rows = append(rows, []string{e.Name, e.Status, yesNo(e.Installed), yesNo(e.Cached)})
+			}
+			return printTable([]string{"NAME", "STATUS", "INSTALLED", "CACHED"}, rows)
+		}
+
+		log.Info("Releases of go available for download are ")
+		for _, release := range releases {
+			fmt.Println("    " + release.Name)
+		}
+		return nil
 	},
 }
+
+// Returns the entries listed for the releases with their status
+func newRemoteEntries(releases []network.Release, statuses []gvm.ReleaseStatus) []remoteEntry {
+	entries := make([]remoteEntry, 0, len(releases))
+	for i, release := range releases {
+		status := "stable"
+		if release.IsPrerelease() {
+			status = "prerelease"
+		}
+		entries = append(entries, remoteEntry{
+			Name:      release.Name,
+			Status:    status,
+			Installed: statuses[i].Installed,
+			Cached:    statuses[i].Cached,
+			Url:       release.DownloadUrl,
+		})
+	}
+	return entries
+}
+
+func init() {
+	listRemoteCmd.Flags().StringVarP(&listRemoteOutput, "output", "o", "",
+		"Format of the listing, json, yaml or table, the names of the releases are listed when not provided")
+	listRemoteCmd.Flags().BoolVar(&listRemoteFilter.Stable, "stable", false,
+		"List only the stable releases")
+	listRemoteCmd.Flags().BoolVar(&listRemoteFilter.Prerelease, "prerelease", false,
+		"List only the betas and release candidates")
+	listRemoteCmd.Flags().StringVar(&listRemoteFilter.Minor, "minor", "",
+		"List only the releases of the minor release, like 1.21")
+	listRemoteCmd.Flags().StringVar(&listRemoteFilter.Since, "since", "",
+		"List only the releases from this one on, like go1.18")
+	listRemoteCmd.Flags().BoolVar(&listRemoteFilter.LatestPerMinor, "latest-per-minor", false,
+		"List only the latest of the listed releases of each minor release")
+	listRemoteCmd.Flags().BoolVar(&listRemoteFilter.Supported, "supported", false,
+		"List only the releases of the two most recent minor releases, which are the supported ones")
+}
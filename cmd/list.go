@@ -1,13 +1,32 @@
 package cmd
 
 import (
-	"io/ioutil"
-	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/fristonio/gvm/gvm"
 	"github.com/fristonio/gvm/utils"
 	"github.com/spf13/cobra"
 )
 
+// Format of the listing, json, yaml or table
+var listOutput string
+
+// Installed version as listed with --output json or yaml
+type installedEntry struct {
+	Name        string    `json:"name" yaml:"name"`
+	Release     string    `json:"release" yaml:"release"`
+	Variant     string    `json:"variant,omitempty" yaml:"variant,omitempty"`
+	Path        string    `json:"path" yaml:"path"`
+	Size        int64     `json:"size" yaml:"size"`
+	InstalledAt time.Time `json:"installed_at" yaml:"installed_at"`
+	Mode        string    `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Pkgsets     []string  `json:"pkgsets" yaml:"pkgsets"`
+	Patched     bool      `json:"patched" yaml:"patched"`
+	Default     bool      `json:"default" yaml:"default"`
+	Current     bool      `json:"current" yaml:"current"`
+}
+
 // Print the version of gvm running and exit gracefully
 // Version information are retrived from the version/version.go which is populated at
 // build time using ldflags
@@ -17,19 +36,65 @@ var listCmd = &cobra.Command{
 	Long: `List all the releases of golang that are available in the local
 gvm environment to use.`,
 
-	Run: func(cmd *cobra.Command, args []string) {
-		gosDir := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_GOS_DIRNAME)
-		gos, err := ioutil.ReadDir(gosDir)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(listOutput); err != nil {
+			return err
+		}
+		installations, err := newManager().ListInstalled()
 		if err != nil {
-			log.Fatal("No gos installed, to view a list of versions available use: go list-remote")
+			return err
+		}
+
+		switch listOutput {
+		case OUTPUT_JSON, OUTPUT_YAML:
+			entries := make([]installedEntry, 0, len(installations))
+			for _, installation := range installations {
+				entries = append(entries, newInstalledEntry(installation))
+			}
+			return printStructured(listOutput, entries)
+		case OUTPUT_TABLE:
+			rows := make([][]string, 0, len(installations))
+			for _, installation := range installations {
+				e := newInstalledEntry(installation)
+				rows = append(rows, []string{e.Name, e.Mode, utils.MemoryBytesToString(e.Size),
+					e.InstalledAt.Local().Format("2006-01-02 15:04"), strings.Join(e.Pkgsets, ","),
+					yesNo(e.Patched), yesNo(e.Default), yesNo(e.Current)})
+			}
+			return printTable([]string{"NAME", "MODE", "SIZE", "INSTALLED", "PKGSETS", "PATCHED", "DEFAULT", "CURRENT"}, rows)
 		}
 
 		var installedGos = make([]string, 0)
-		for _, f := range gos {
-			if f.IsDir() && utils.GOS_REGEXP.FindString(f.Name()) != "" {
-				installedGos = append(installedGos, f.Name())
+		var labels = make(map[string]string)
+		for _, installation := range installations {
+			if installation.Meta.IsPatched() {
+				labels[installation.Name] = " (patched)"
 			}
+			installedGos = append(installedGos, installation.Name)
 		}
-		utils.PrintInstalledGos(installedGos)
+		utils.PrintInstalledGos(installedGos, labels)
+		return nil
 	},
 }
+
+// Returns the entry listed for the installation
+func newInstalledEntry(installation gvm.Installation) installedEntry {
+	release, variant := utils.SplitGoName(installation.Name)
+	return installedEntry{
+		Name:        installation.Name,
+		Release:     release,
+		Variant:     variant,
+		Path:        installation.Path,
+		Size:        installation.Size(),
+		InstalledAt: installation.InstalledAt,
+		Mode:        installation.Meta.Mode,
+		Pkgsets:     installation.Pkgsets,
+		Patched:     installation.Meta.IsPatched(),
+		Default:     installation.Default,
+		Current:     installation.Current,
+	}
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "",
+		"Format of the listing, json, yaml or table, the versions are listed by name when not provided")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fristonio/gvm/manager"
+	"github.com/fristonio/gvm/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsSelftest bool
+	logsPath     bool
+)
+
+// Print the last build log of a version of go
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Displays the last build log of the specified version of go",
+	Long: `Displays the log of the last compilation of the version specified as the argument.
+Logs are kept in the logs directory under gvm root, for example ~/.gvm/logs/go1.9`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return noVersionError(`gvm logs [go version]
+    gvm logs go1.9`)
+		}
+
+		kind := manager.BUILD_LOG
+		if logsSelftest {
+			kind = manager.SELFTEST_LOG
+		}
+		logFile, err := newManager().LogFile(args[0], kind)
+		if err != nil {
+			return err
+		}
+
+		if logsPath {
+			fmt.Println(logFile)
+			return nil
+		}
+
+		f, err := os.Open(logFile)
+		if err != nil {
+			return utils.WrapError(utils.GENERIC_ERROR, err, "Error while opening log")
+		}
+		defer f.Close()
+		_, err = io.Copy(os.Stdout, f)
+		return err
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVar(&logsSelftest, "selftest", false, "Display the last self test log instead")
+	logsCmd.Flags().BoolVar(&logsPath, "path", false, "Only print the path of the log file")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fristonio/gvm/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// Formats of the output of the list commands
+const (
+	OUTPUT_JSON  = "json"
+	OUTPUT_YAML  = "yaml"
+	OUTPUT_TABLE = "table"
+)
+
+// Check that the output format is one of the supported ones, an empty format
+// selects the listing by name.
+func checkOutputFormat(format string) error {
+	switch format {
+	case "", OUTPUT_JSON, OUTPUT_YAML, OUTPUT_TABLE:
+		return nil
+	}
+	return utils.NewError(utils.USAGE_ERROR, "Unknown output format %q, it should be one of json, yaml or table", format)
+}
+
+// Print the value as json or yaml on stdout
+func printStructured(format string, v interface{}) error {
+	var out []byte
+	var err error
+	if format == OUTPUT_YAML {
+		out, err = yaml.Marshal(v)
+	} else {
+		out, err = json.MarshalIndent(v, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return utils.WrapError(utils.GENERIC_ERROR, err, "Error while formatting the output")
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// Print the rows as a table with aligned columns on stdout
+func printTable(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// Returns yes or no for a table cell
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Run the go test suite for an installed version and record the result
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Run the go test suite for an installed version of go",
+	Long: `Runs the test suite (run.bash) of go for the installed version specified as the argument
+and records if it passed in the metadata of the installation. A version whose self test
+failed cannot be made the default one.`,
+
+	Annotations: recordedCommand,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return noVersionError(`gvm selftest [go version]
+    gvm selftest go1.9
+    To list version available for use : gvm list`)
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+		_, err := newManager().SelfTest(ctx, args[0], quiet)
+		return err
+	},
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	useDefault bool
+	useForce   bool
+)
+
+// Print the command to activate the environment of a go version, it is to be
+// evaluated by the shell as eval "$(gvm use go1.9)"
+var useCmd = &cobra.Command{
+	Use:   "use",
+	Short: "Activate the specified version of go",
+	Long: `Prints the command to source the environment file of the version specified as the
+argument, to be evaluated by the shell using : eval "$(gvm use go1.9)"
+With --default the version is marked as the default one, whose environment file is
+available as ~/.gvm/environment/default`,
+
+	Annotations: recordedCommand,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return noVersionError(`gvm use [go version]
+    gvm use go1.9
+    To list version available for use : gvm list`)
+		}
+
+		releaseName := args[0]
+		m := newManager()
+		envFile, err := m.EnvFile(releaseName)
+		if err != nil {
+			return err
+		}
+
+		if useDefault {
+			if err := m.SetDefault(releaseName, useForce); err != nil {
+				return err
+			}
+			log.Infof("%s is now the default version", releaseName)
+		}
+		fmt.Printf("source %s\n", envFile)
+		return nil
+	},
+}
+
+func init() {
+	useCmd.Flags().BoolVar(&useDefault, "default", false, "Mark the version as the default one")
+	useCmd.Flags().BoolVar(&useForce, "force", false, "Mark the version as default even if its self test failed")
+}
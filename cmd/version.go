@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/fristonio/gvm/version"
 	"github.com/spf13/cobra"
@@ -15,7 +14,7 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Displays the version of the current build of gvm",
 	Long:  `Displays the version of the current build of gvm`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Printf(version.VersionStr,
 			version.Info["version"],
 			version.Info["revision"],
@@ -23,6 +22,6 @@ var versionCmd = &cobra.Command{
 			version.Info["buildUser"],
 			version.Info["buildDate"],
 			version.Info["goVersion"])
-		os.Exit(0)
+		return nil
 	},
 }
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fristonio/gvm/gvm"
+	"github.com/fristonio/gvm/manager"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Name of the configuration file in gvm root
+const CONFIG_FILE string = "config.toml"
+
+// Policies for the archives of the installed releases
+const (
+	// Keep the archives in the downloads directory to reinstall without downloading
+	CACHE_KEEP string = "keep"
+	// Remove the archive from the downloads directory once it is installed
+	CACHE_CLEAN string = "clean"
+	// Stream the archives without storing them, as with install --no-cache
+	CACHE_NONE string = "none"
+)
+
+// Defaults of gvm, read from the configuration file in gvm root
+type Config struct {
+	// Mirrors of the release archives, tried in order
+	Mirrors []string `toml:"mirrors"`
+	// Number of connections used for downloading an archive
+	Connections int `toml:"connections"`
+	// Maximum download rate like 2M, empty for no limit
+	LimitRate string `toml:"limit_rate"`
+	// Mode of installation, source or binary
+	InstallMode string `toml:"install_mode"`
+	// Name of the package set of the environments
+	Pkgset string `toml:"pkgset"`
+	// Policy for the archives of the installed releases, keep, clean or none
+	Cache string `toml:"cache"`
+	// Options for the compilation when none are provided for an install
+	Build manager.BuildOptions `toml:"build"`
+}
+
+// Returns the path of the configuration file in gvm root
+func DefaultPath() string {
+	return filepath.Join(utils.GVM_ROOT_DIR, CONFIG_FILE)
+}
+
+// Returns the configuration used when there is no configuration file
+func Default() *Config {
+	return &Config{
+		Mirrors:     []string{},
+		Connections: gvm.DEFAULT_CONNECTIONS,
+		InstallMode: manager.SOURCE_INSTALL,
+		Pkgset:      "global",
+		Cache:       CACHE_KEEP,
+	}
+}
+
+// Read the configuration file at path, the keys missing from the file keep their
+// default value. The default configuration is returned if the file does not exist.
+func Load(path string) (*Config, error) {
+	c, err := LoadLenient(path)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Read the configuration file at path like Load, except that a file with unknown
+// keys or invalid values is returned along with the error so that it can still be
+// viewed and fixed. Only a file which can not be parsed returns a nil configuration.
+func LoadLenient(path string) (*Config, error) {
+	c := Default()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return c, nil
+	}
+
+	meta, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid configuration file %s : %v", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return c, fmt.Errorf("Unknown key %s in configuration file %s", undecoded[0], path)
+	}
+	if err = c.Validate(); err != nil {
+		return c, fmt.Errorf("Invalid configuration file %s : %v", path, err)
+	}
+	return c, nil
+}
+
+// Write the configuration to the file at path, replacing it
+func (c *Config) Save(path string) error {
+	if err := utils.CreateDirStrucutre(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Defaults of gvm, edit with : gvm config set <key> <value>\n\n")
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return err
+	}
+	partial := path + ".partial"
+	if err := ioutil.WriteFile(partial, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// Checks of the values of the configuration, each one for the key it is named
+// after. The build options are checked one by one so that an invalid option does
+// not prevent setting the other ones.
+var checks = []struct {
+	key   string
+	check func(c *Config) error
+}{
+	{"connections", func(c *Config) error {
+		if c.Connections < 1 {
+			return fmt.Errorf("connections should be at least 1, got %d", c.Connections)
+		}
+		return nil
+	}},
+	{"limit_rate", func(c *Config) error {
+		if c.LimitRate != "" {
+			if _, err := utils.ParseByteSize(c.LimitRate); err != nil {
+				return fmt.Errorf("limit_rate : %v", err)
+			}
+		}
+		return nil
+	}},
+	{"install_mode", func(c *Config) error {
+		if c.InstallMode != manager.SOURCE_INSTALL && c.InstallMode != manager.BINARY_INSTALL {
+			return fmt.Errorf("install_mode should be source or binary, got %q", c.InstallMode)
+		}
+		return nil
+	}},
+	{"pkgset", func(c *Config) error {
+		if c.Pkgset == "" || strings.ContainsAny(c.Pkgset, `/\`) || c.Pkgset == "." || c.Pkgset == ".." {
+			return fmt.Errorf("pkgset should be the name of a directory, got %q", c.Pkgset)
+		}
+		return nil
+	}},
+	{"cache", func(c *Config) error {
+		if c.Cache != CACHE_KEEP && c.Cache != CACHE_CLEAN && c.Cache != CACHE_NONE {
+			return fmt.Errorf("cache should be one of keep, clean or none, got %q", c.Cache)
+		}
+		return nil
+	}},
+	{"build.cgo_enabled", func(c *Config) error {
+		if err := (manager.BuildOptions{CgoEnabled: c.Build.CgoEnabled}).Validate(); err != nil {
+			return fmt.Errorf("build.cgo_enabled : %v", err)
+		}
+		return nil
+	}},
+	{"build.microarch", func(c *Config) error {
+		if err := (manager.BuildOptions{MicroArch: c.Build.MicroArch}).Validate(); err != nil {
+			return fmt.Errorf("build.microarch : %v", err)
+		}
+		return nil
+	}},
+}
+
+// Check that the values of the configuration can be used
+func (c *Config) Validate() error {
+	for _, check := range checks {
+		if err := check.check(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check that the value of the key can be used, regardless of the other keys
+func (c *Config) validateKey(key string) error {
+	for _, check := range checks {
+		if key == check.key {
+			return check.check(c)
+		}
+	}
+	return nil
+}
+
+// Accessors for a key of the configuration as a string
+type setting struct {
+	get func(c *Config) string
+	set func(c *Config, value string) error
+}
+
+// Returns the setting for a string field
+func stringSetting(field func(c *Config) *string) setting {
+	return setting{
+		get: func(c *Config) string { return *field(c) },
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+	}
+}
+
+var settings = map[string]setting{
+	"mirrors": {
+		get: func(c *Config) string { return strings.Join(c.Mirrors, ",") },
+		set: func(c *Config, value string) error {
+			c.Mirrors = []string{}
+			for _, mirror := range strings.Split(value, ",") {
+				if mirror = strings.TrimSpace(mirror); mirror != "" {
+					c.Mirrors = append(c.Mirrors, mirror)
+				}
+			}
+			return nil
+		},
+	},
+	"connections": {
+		get: func(c *Config) string { return strconv.Itoa(c.Connections) },
+		set: func(c *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("connections should be a number, got %q", value)
+			}
+			c.Connections = n
+			return nil
+		},
+	},
+	"limit_rate":         stringSetting(func(c *Config) *string { return &c.LimitRate }),
+	"install_mode":       stringSetting(func(c *Config) *string { return &c.InstallMode }),
+	"pkgset":             stringSetting(func(c *Config) *string { return &c.Pkgset }),
+	"cache":              stringSetting(func(c *Config) *string { return &c.Cache }),
+	"build.cgo_enabled":  stringSetting(func(c *Config) *string { return &c.Build.CgoEnabled }),
+	"build.goexperiment": stringSetting(func(c *Config) *string { return &c.Build.Experiment }),
+	"build.gcflags":      stringSetting(func(c *Config) *string { return &c.Build.GcFlags }),
+	"build.ldflags":      stringSetting(func(c *Config) *string { return &c.Build.LdFlags }),
+	"build.microarch":    stringSetting(func(c *Config) *string { return &c.Build.MicroArch }),
+	"build.no_clean": {
+		get: func(c *Config) string { return strconv.FormatBool(c.Build.NoClean) },
+		set: func(c *Config, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("build.no_clean should be true or false, got %q", value)
+			}
+			c.Build.NoClean = b
+			return nil
+		},
+	},
+}
+
+// Returns the keys of the configuration in lexical order
+func Keys() []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the value of the key, lists like the mirrors are separated with commas
+func (c *Config) Get(key string) (string, error) {
+	s, ok := settings[key]
+	if !ok {
+		return "", unknownKeyError(key)
+	}
+	return s.get(c), nil
+}
+
+// Set the key to the value, lists like the mirrors are separated with commas.
+// The configuration is left unchanged if the value is invalid, invalid values of
+// the other keys do not prevent setting it so that they can be fixed one by one.
+func (c *Config) Set(key string, value string) error {
+	s, ok := settings[key]
+	if !ok {
+		return unknownKeyError(key)
+	}
+
+	updated := *c
+	updated.Mirrors = append([]string{}, c.Mirrors...)
+	if err := s.set(&updated, value); err != nil {
+		return err
+	}
+	if err := updated.validateKey(key); err != nil {
+		return err
+	}
+	*c = updated
+	return nil
+}
+
+func unknownKeyError(key string) error {
+	return fmt.Errorf("Unknown key %q, it should be one of %s", key, strings.Join(Keys(), ", "))
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Write the content to a configuration file in a temporary directory, returns
+// its path and the function removing it.
+func tempConfig(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "gvm-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, CONFIG_FILE)
+	if content != "" {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// Reports if Load fails, and if LoadLenient fails to return the configuration too
+		invalid     bool
+		unparseable bool
+		connections int
+	}{
+		{name: "missing file", connections: 4},
+		{name: "partial file", content: "connections = 8\n", connections: 8},
+		{name: "syntax error", content: "connections = \n", invalid: true, unparseable: true},
+		{name: "unknown key", content: "connections = 2\ncolor = true\n", invalid: true, connections: 2},
+		{name: "invalid value", content: "connections = 0\n", invalid: true, connections: 0},
+		{name: "invalid build option", content: "[build]\ncgo_enabled = \"2\"\n", invalid: true, connections: 4},
+	}
+	for _, test := range tests {
+		path, remove := tempConfig(t, test.content)
+		c, err := Load(path)
+		if (err != nil) != test.invalid || (err == nil) != (c != nil) {
+			t.Errorf("%s : Load returned %+v, %v", test.name, c, err)
+		}
+
+		c, err = LoadLenient(path)
+		if (err != nil) != test.invalid || (c == nil) != test.unparseable {
+			t.Errorf("%s : LoadLenient returned %+v, %v", test.name, c, err)
+		} else if c != nil && c.Connections != test.connections {
+			t.Errorf("%s : loaded %d connections, expected %d", test.name, c.Connections, test.connections)
+		}
+		remove()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("default configuration is invalid : %v", err)
+	}
+
+	invalid := map[string]func(c *Config){
+		"connections":  func(c *Config) { c.Connections = 0 },
+		"limit_rate":   func(c *Config) { c.LimitRate = "fast" },
+		"install_mode": func(c *Config) { c.InstallMode = "copy" },
+		"pkgset":       func(c *Config) { c.Pkgset = "../global" },
+		"dot pkgset":   func(c *Config) { c.Pkgset = ".." },
+		"cache":        func(c *Config) { c.Cache = "always" },
+		"cgo_enabled":  func(c *Config) { c.Build.CgoEnabled = "yes" },
+		"microarch":    func(c *Config) { c.Build.MicroArch = "v9" },
+	}
+	for name, change := range invalid {
+		c := Default()
+		change(c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("configuration with an invalid %s is valid", name)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	c := Default()
+	values := map[string]string{
+		"mirrors":           "/mnt/go-archives, https://mirror.example.com/go,",
+		"connections":       "8",
+		"limit_rate":        "2M",
+		"install_mode":      "binary",
+		"pkgset":            "work",
+		"cache":             "clean",
+		"build.cgo_enabled": "0",
+		"build.no_clean":    "true",
+	}
+	for key, value := range values {
+		if err := c.Set(key, value); err != nil {
+			t.Errorf("setting %s to %q failed : %v", key, value, err)
+		}
+	}
+	expected := &Config{
+		Mirrors:     []string{"/mnt/go-archives", "https://mirror.example.com/go"},
+		Connections: 8,
+		LimitRate:   "2M",
+		InstallMode: "binary",
+		Pkgset:      "work",
+		Cache:       "clean",
+	}
+	expected.Build.CgoEnabled = "0"
+	expected.Build.NoClean = true
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("configuration is %+v, expected %+v", c, expected)
+	}
+	if value, err := c.Get("mirrors"); err != nil || value != "/mnt/go-archives,https://mirror.example.com/go" {
+		t.Errorf("mirrors are %q, %v", value, err)
+	}
+
+	// Invalid values leave the configuration unchanged
+	for key, value := range map[string]string{
+		"connections":    "many",
+		"connections ":   "4",
+		"cache":          "always",
+		"build.no_clean": "maybe",
+		"color":          "true",
+	} {
+		if err := c.Set(key, value); err == nil {
+			t.Errorf("setting %q to %q succeeded", key, value)
+		}
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("configuration changed to %+v", c)
+	}
+
+	// A key is set while another one is invalid, which is then fixed
+	c.Connections = 0
+	if err := c.Set("cache", "keep"); err != nil || c.Cache != "keep" {
+		t.Errorf("setting cache with invalid connections failed : %v", err)
+	}
+	if err := c.Set("connections", "2"); err != nil || c.Validate() != nil {
+		t.Errorf("fixing connections failed : %v", err)
+	}
+
+	// The build options are checked one by one
+	c.Build.CgoEnabled = "2"
+	if err := c.Set("build.gcflags", "all=-N"); err != nil || c.Build.GcFlags != "all=-N" {
+		t.Errorf("setting build.gcflags with an invalid build.cgo_enabled failed : %v", err)
+	}
+	if err := c.Set("build.cgo_enabled", "1"); err != nil || c.Validate() != nil {
+		t.Errorf("fixing build.cgo_enabled failed : %v", err)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path, remove := tempConfig(t, "")
+	defer remove()
+
+	c := Default()
+	c.Mirrors = []string{"/mnt/go-archives"}
+	c.Build.GcFlags = "-N"
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, c) {
+		t.Errorf("loaded %+v, saved %+v", loaded, c)
+	}
+}
@@ -0,0 +1,695 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Archive formats which can be extracted
+type Format string
+
+const (
+	TAR     Format = "tar"
+	TAR_GZ  Format = "tar.gz"
+	TAR_XZ  Format = "tar.xz"
+	TAR_ZST Format = "tar.zst"
+	TAR_BZ2 Format = "tar.bz2"
+	ZIP     Format = "zip"
+)
+
+// Magic bytes at the start of the compressed formats
+var magics = []struct {
+	format Format
+	magic  []byte
+}{
+	{TAR_GZ, []byte{0x1f, 0x8b}},
+	{TAR_XZ, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{TAR_ZST, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{TAR_BZ2, []byte{'B', 'Z', 'h'}},
+	{ZIP, []byte{'P', 'K', 0x03, 0x04}},
+	{ZIP, []byte{'P', 'K', 0x05, 0x06}},
+}
+
+// Offset and value of the magic of an uncompressed tar archive
+const (
+	tarMagicOffset = 257
+	tarMagic       = "ustar"
+)
+
+// Options for the extraction of an archive
+type Options struct {
+	// Number of leading path components removed from the name of each entry,
+	// entries which are left with an empty name are skipped. The official binary
+	// distributions are rooted at go/ so they are extracted with 1.
+	StripComponents int
+	// Maximum total size in bytes of the extracted files, 0 for no limit
+	MaxSize int64
+	// Maximum number of entries extracted, 0 for no limit
+	MaxFiles int
+}
+
+// Default limit on the number of entries extracted, a go source tree or binary
+// distribution has well below it.
+const DEFAULT_MAX_FILES = 100000
+
+// Detect the format of the archive from the magic bytes at its start
+func DetectFormat(r io.Reader) (Format, error) {
+	header := make([]byte, tarMagicOffset+len(tarMagic))
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	header = header[:n]
+
+	for _, m := range magics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.format, nil
+		}
+	}
+	if len(header) == tarMagicOffset+len(tarMagic) && string(header[tarMagicOffset:]) == tarMagic {
+		return TAR, nil
+	}
+	return "", fmt.Errorf("Unknown archive format")
+}
+
+// Extract the archive source to destination, the format of the archive is detected
+// from its content. Destination is removed before extracting if it already exists.
+// Directories, regular files, symbolic and hard links are extracted with their
+// permissions and modification times, other entries are skipped and reported in
+// the returned summary. An *UnsafeArchiveError is returned for an entry which would
+// be created outside of destination or exceed the limits of the options. The
+// extraction stops with the error of ctx once it is done.
+func Extract(ctx context.Context, source string, destination string, opts Options) (*Summary, error) {
+	file, err := os.Open(source)
+	if err != nil {
+		return &Summary{}, err
+	}
+	defer file.Close()
+
+	format, err := DetectFormat(file)
+	if err != nil {
+		return &Summary{}, fmt.Errorf("%s : %v", source, err)
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return &Summary{}, err
+	}
+	if format != ZIP {
+		return ExtractReader(ctx, file, destination, opts)
+	}
+
+	summary := &Summary{Skipped: make([]SkippedEntry, 0)}
+	if err := prepareDestination(destination); err != nil {
+		return summary, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return summary, err
+	}
+	zr, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return summary, err
+	}
+	return summary, extractZip(ctx, zr, destination, opts, summary)
+}
+
+// Extract the archive read from r to destination like Extract, the archive is read
+// sequentially so it can be streamed while it is downloaded. Zip archives can not be
+// extracted this way as their index is at the end of the archive.
+func ExtractReader(ctx context.Context, r io.Reader, destination string, opts Options) (*Summary, error) {
+	summary := &Summary{Skipped: make([]SkippedEntry, 0)}
+	br := bufio.NewReader(r)
+	header, err := br.Peek(tarMagicOffset + len(tarMagic))
+	if err != nil && err != io.EOF {
+		return summary, err
+	}
+
+	format, err := DetectFormat(bytes.NewReader(header))
+	if err != nil {
+		return summary, err
+	}
+	if format == ZIP {
+		return summary, fmt.Errorf("Zip archives can not be extracted while streaming")
+	}
+
+	if err := prepareDestination(destination); err != nil {
+		return summary, err
+	}
+	tr, err := decompress(br, format)
+	if err != nil {
+		return summary, err
+	}
+	defer tr.Close()
+	return summary, extractTar(ctx, tar.NewReader(tr), destination, opts, summary)
+}
+
+// Remove the destination if it already exists and create it empty
+func prepareDestination(destination string) error {
+	if _, err := os.Stat(destination); err == nil {
+		os.RemoveAll(destination)
+	}
+	return os.MkdirAll(destination, 0755)
+}
+
+// Returns a reader decompressing the tar archive in the format
+func decompress(r io.Reader, format Format) (io.ReadCloser, error) {
+	switch format {
+	case TAR:
+		return ioutil.NopCloser(r), nil
+	case TAR_GZ:
+		return gzip.NewReader(r)
+	case TAR_XZ:
+		xzr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(xzr), nil
+	case TAR_ZST:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case TAR_BZ2:
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return nil, fmt.Errorf("Unsupported archive format %s", format)
+}
+
+// Returns the name of the entry with the leading components stripped as per
+// the options, empty if nothing is left of it.
+func stripName(name string, opts Options) string {
+	name = strings.TrimPrefix(name, "./")
+	for i := 0; i < opts.StripComponents && name != ""; i++ {
+		idx := strings.Index(name, "/")
+		if idx < 0 {
+			return ""
+		}
+		name = name[idx+1:]
+	}
+	return name
+}
+
+// Error returned for an archive which can not be extracted safely, either because
+// an entry would be written outside of the destination or because the archive
+// exceeds the limits of the extraction.
+type UnsafeArchiveError struct {
+	Entry  string
+	Reason string
+}
+
+func (e *UnsafeArchiveError) Error() string {
+	return fmt.Sprintf("Unsafe archive entry %s : %s", e.Entry, e.Reason)
+}
+
+// Entry of an archive which was not extracted
+type SkippedEntry struct {
+	Name   string
+	Reason string
+}
+
+// Summary of an extraction
+type Summary struct {
+	Files    int
+	Dirs     int
+	Symlinks int
+	Links    int
+	// Total size of the extracted files in bytes
+	Size    int64
+	Skipped []SkippedEntry
+}
+
+func (s *Summary) skip(name string, reason string) {
+	s.Skipped = append(s.Skipped, SkippedEntry{Name: name, Reason: reason})
+}
+
+// Returns the number of entries extracted
+func (s *Summary) entries() int {
+	return s.Files + s.Dirs + s.Symlinks + s.Links
+}
+
+// Modification time and permissions to set on a directory once all its entries
+// are extracted, as creating entries in a directory updates its modification time
+// and needs the directory to be writable.
+type dirTime struct {
+	path  string
+	mtime time.Time
+	mode  os.FileMode
+}
+
+// Returns the permission bits of mode which are set on extracted entries
+func permissions(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// Error returned by writeFile when the file exceeds the size left for the extraction
+var errSizeExceeded = errors.New("size limit exceeded")
+
+// Creates the entries of an archive in the destination, every entry is checked
+// to stay inside the destination and within the limits of the options.
+type extractor struct {
+	destination string
+	opts        Options
+	summary     *Summary
+	dirTimes    []dirTime
+	// Extracted symbolic links, checked again once all the entries are extracted
+	symlinks []extractedLink
+}
+
+// Symbolic link created in the destination
+type extractedLink struct {
+	entry    string
+	name     string
+	linkName string
+}
+
+// Maximum number of symbolic links followed while resolving a link target
+const maxLinkHops = 40
+
+func newExtractor(destination string, opts Options, summary *Summary) *extractor {
+	return &extractor{
+		destination: destination,
+		opts:        opts,
+		summary:     summary,
+		dirTimes:    make([]dirTime, 0),
+		symlinks:    make([]extractedLink, 0),
+	}
+}
+
+// Returns the cleaned name of the entry relative to the destination after stripping
+// the leading components, empty if the entry is to be skipped. Entries with an
+// absolute path or escaping the destination are rejected.
+func (e *extractor) entryName(name string) (string, error) {
+	slashed := filepath.ToSlash(name)
+	if path.IsAbs(slashed) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", &UnsafeArchiveError{name, "absolute path"}
+	}
+
+	stripped := stripName(slashed, e.opts)
+	if stripped == "" {
+		return "", nil
+	}
+	cleaned := path.Clean(stripped)
+	if cleaned == "." {
+		return "", nil
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", &UnsafeArchiveError{name, "path escapes the destination"}
+	}
+	return cleaned, nil
+}
+
+// Returns the path where the entry is to be created, none of the directories leading
+// to it in the destination can be a symbolic link so that a link extracted earlier
+// can not be used to write outside of the destination.
+func (e *extractor) target(entry string, name string) (string, error) {
+	if e.opts.MaxFiles > 0 && e.summary.entries() >= e.opts.MaxFiles {
+		return "", &UnsafeArchiveError{entry, fmt.Sprintf("archive has more than %d entries", e.opts.MaxFiles)}
+	}
+
+	parent := e.destination
+	components := strings.Split(name, "/")
+	for _, c := range components[:len(components)-1] {
+		parent = filepath.Join(parent, c)
+		info, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", &UnsafeArchiveError{entry, "path is inside a symbolic link"}
+		}
+	}
+	return filepath.Join(e.destination, filepath.FromSlash(name)), nil
+}
+
+// Create the directory with its parents, it is kept writable by the owner until
+// its permissions are set once the extraction is done.
+func (e *extractor) dir(entry string, name string, mode os.FileMode, mtime time.Time) error {
+	target, err := e.target(entry, name)
+	if err != nil {
+		return err
+	}
+	// An existing symbolic link would be followed by MkdirAll
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return &UnsafeArchiveError{entry, "directory replaces a symbolic link"}
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, mode|0700); err != nil {
+		return err
+	}
+	e.dirTimes = append(e.dirTimes, dirTime{target, mtime, mode})
+	e.summary.Dirs++
+	return nil
+}
+
+// Create the file with the contents of r, setting its permissions and modification time
+func (e *extractor) file(entry string, name string, r io.Reader, mode os.FileMode, mtime time.Time) error {
+	target, err := e.target(entry, name)
+	if err != nil {
+		return err
+	}
+
+	limit := int64(-1)
+	if e.opts.MaxSize > 0 {
+		limit = e.opts.MaxSize - e.summary.Size
+	}
+	n, err := writeFile(target, r, mode, mtime, limit)
+	e.summary.Size += n
+	if err == errSizeExceeded {
+		return &UnsafeArchiveError{entry, fmt.Sprintf("extracted size exceeds %d bytes", e.opts.MaxSize)}
+	}
+	if err != nil {
+		return err
+	}
+	e.summary.Files++
+	return nil
+}
+
+// Create a symbolic link pointing to linkName, which must be relative and resolve
+// to a path inside the destination through the links already extracted.
+func (e *extractor) symlink(entry string, name string, linkName string) error {
+	slashed := filepath.ToSlash(linkName)
+	if path.IsAbs(slashed) || filepath.IsAbs(linkName) || filepath.VolumeName(linkName) != "" {
+		return &UnsafeArchiveError{entry, fmt.Sprintf("absolute symbolic link target %s", linkName)}
+	}
+	if err := e.checkLink(entry, name, linkName); err != nil {
+		return err
+	}
+
+	target, err := e.target(entry, name)
+	if err != nil {
+		return err
+	}
+	if err := makeSymlink(target, linkName); err != nil {
+		return err
+	}
+	e.symlinks = append(e.symlinks, extractedLink{entry, name, linkName})
+	e.summary.Symlinks++
+	return nil
+}
+
+// Check that the target of the symbolic link at name resolves to a path inside
+// the destination, following the symbolic links extracted in the destination.
+func (e *extractor) checkLink(entry string, name string, linkName string) error {
+	dir := strings.Split(path.Dir(name), "/")
+	if dir[0] == "." {
+		dir = nil
+	}
+	hops := 0
+	if _, err := e.resolve(dir, linkName, &hops); err != nil {
+		return &UnsafeArchiveError{entry, fmt.Sprintf("symbolic link target %s %v", linkName, err)}
+	}
+	return nil
+}
+
+// Returns the components of the path of linkName relative to the destination, when
+// followed from the directory with the components dir. Symbolic links found in the
+// destination along the way are followed, a path which does not exist is resolved
+// as is. An error is returned if the path leads outside of the destination.
+func (e *extractor) resolve(dir []string, linkName string, hops *int) ([]string, error) {
+	resolved := append([]string{}, dir...)
+	for _, c := range strings.Split(filepath.ToSlash(linkName), "/") {
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return nil, errors.New("escapes the destination")
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, c)
+		current := filepath.Join(e.destination, filepath.Join(resolved...))
+		info, err := os.Lstat(current)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if *hops++; *hops > maxLinkHops {
+			return nil, errors.New("goes through too many symbolic links")
+		}
+		next, err := os.Readlink(current)
+		if err != nil {
+			return nil, err
+		}
+		if path.IsAbs(filepath.ToSlash(next)) || filepath.IsAbs(next) {
+			return nil, errors.New("goes through an absolute symbolic link")
+		}
+		if resolved, err = e.resolve(resolved[:len(resolved)-1], next, hops); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// Create a hard link to the already extracted entry linkName, hard links are
+// relative to the root of the archive.
+func (e *extractor) link(entry string, name string, linkName string) error {
+	linkEntry, err := e.entryName(linkName)
+	if err != nil {
+		return err
+	}
+	if linkEntry == "" {
+		e.summary.skip(entry, "link target is stripped")
+		return nil
+	}
+	linkTarget, err := e.target(entry, linkEntry)
+	if err != nil {
+		return err
+	}
+	target, err := e.target(entry, name)
+	if err != nil {
+		return err
+	}
+	if err := makeLink(target, linkTarget); err != nil {
+		return err
+	}
+	e.summary.Links++
+	return nil
+}
+
+// Check the extracted symbolic links again, a link created later in the archive
+// could make the target of an earlier one lead outside of the destination. Then set
+// the permissions and modification time of the extracted directories, deepest first.
+func (e *extractor) finish() error {
+	for _, link := range e.symlinks {
+		if err := e.checkLink(link.entry, link.name, link.linkName); err != nil {
+			return err
+		}
+	}
+	for i := len(e.dirTimes) - 1; i >= 0; i-- {
+		if err := os.Chmod(e.dirTimes[i].path, e.dirTimes[i].mode); err != nil {
+			return err
+		}
+		if err := os.Chtimes(e.dirTimes[i].path, e.dirTimes[i].mtime, e.dirTimes[i].mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTar(ctx context.Context, tr *tar.Reader, destination string, opts Options, summary *Summary) error {
+	e := newExtractor(destination, opts, summary)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+
+		switch {
+
+		// if no more files are found set the time of the directories and return
+		case err == io.EOF:
+			return e.finish()
+
+		// return any other error
+		case err != nil:
+			return err
+
+		// if the header is nil, just skip it (not sure how this happens)
+		case header == nil:
+			continue
+		}
+
+		// global pax headers only carry metadata
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		name, err := e.entryName(header.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		mode := permissions(header.FileInfo().Mode())
+
+		// check the file type
+		switch header.Typeflag {
+
+		case tar.TypeDir:
+			err = e.dir(header.Name, name, mode, header.ModTime)
+
+		case tar.TypeReg, tar.TypeRegA:
+			err = e.file(header.Name, name, tr, mode, header.ModTime)
+
+		case tar.TypeSymlink:
+			err = e.symlink(header.Name, name, header.Linkname)
+
+		case tar.TypeLink:
+			err = e.link(header.Name, name, header.Linkname)
+
+		default:
+			summary.skip(header.Name, fmt.Sprintf("unsupported entry type %q", header.Typeflag))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(ctx context.Context, zr *zip.Reader, destination string, opts Options, summary *Summary) error {
+	e := newExtractor(destination, opts, summary)
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name, err := e.entryName(f.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		mode := f.Mode()
+
+		switch {
+		case mode.IsDir():
+			err = e.dir(f.Name, name, permissions(mode), f.Modified)
+
+		// content of a symlink entry is the target of the link
+		case mode&os.ModeSymlink != 0:
+			var linkName []byte
+			linkName, err = readZipFile(f)
+			if err == nil {
+				err = e.symlink(f.Name, name, string(linkName))
+			}
+
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			rc, err = f.Open()
+			if err == nil {
+				err = e.file(f.Name, name, rc, permissions(mode), f.Modified)
+				rc.Close()
+			}
+
+		default:
+			summary.skip(f.Name, fmt.Sprintf("unsupported file mode %s", mode))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.finish()
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// Create the file at target with the contents of r, setting its permissions
+// and modification time. At most limit bytes are written when it is not negative,
+// errSizeExceeded is returned if the contents are larger.
+func writeFile(target string, r io.Reader, mode os.FileMode, mtime time.Time, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+	// Remove any previous entry, which could be a link to some other file
+	if err := removeExisting(target); err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, mode)
+	if err != nil {
+		return 0, err
+	}
+
+	// copy over contents, reading a byte more than the limit to detect larger files
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	n, err := io.Copy(f, r)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return n, err
+	}
+	if limit >= 0 && n > limit {
+		return limit, errSizeExceeded
+	}
+
+	// permissions of a created file are masked by the umask
+	if err = os.Chmod(target, mode); err != nil {
+		return n, err
+	}
+	return n, os.Chtimes(target, mtime, mtime)
+}
+
+// Create a symbolic link at target pointing to linkName
+func makeSymlink(target string, linkName string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := removeExisting(target); err != nil {
+		return err
+	}
+	return os.Symlink(linkName, target)
+}
+
+// Create a hard link at target to the already extracted file linkTarget
+func makeLink(target string, linkTarget string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := removeExisting(target); err != nil {
+		return err
+	}
+	return os.Link(linkTarget, target)
+}
+
+func removeExisting(target string) error {
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,551 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Entry of a test archive
+type testEntry struct {
+	name string
+	// tar.TypeReg, tar.TypeDir, tar.TypeSymlink or tar.TypeLink
+	typ  byte
+	link string
+	body string
+	// Permissions of the entry, the default ones are used when it is zero
+	mode int64
+}
+
+func file(name string, body string) testEntry {
+	return testEntry{name: name, typ: tar.TypeReg, body: body}
+}
+
+func dir(name string) testEntry {
+	return testEntry{name: name, typ: tar.TypeDir}
+}
+
+func symlink(name string, link string) testEntry {
+	return testEntry{name: name, typ: tar.TypeSymlink, link: link}
+}
+
+func hardlink(name string, link string) testEntry {
+	return testEntry{name: name, typ: tar.TypeLink, link: link}
+}
+
+var testEntries = []testEntry{
+	dir("go/"),
+	dir("go/bin/"),
+	file("go/bin/go", "binary"),
+	file("go/VERSION", "go1.9"),
+}
+
+// The entries of testEntries archived with tar and compressed with bzip2, as
+// compress/bzip2 has no writer.
+const bz2Archive = `QlpoOTFBWSZTWcMWcuYAANJ/gMmAAgBAAf+gAiGZonChniAIiDAAuKGkmgAAADIB6nqGGhkyBkYg
+xMmhpgKokp+kyD1TTT1MEaaNNGJ4y6Z2jWmFXaSIywiEQrs1upRc0jEsuyuMCISRMMOtidioipnK
+A+OXPneF+8zQ1aKnA4yVLDCPI86SosnFvvuUzYpkAUIljOlrGMbBsoi9AxWPlcIsvCMjBGQQBAED
+9sQf4u5IpwoSGGLOXMA=`
+
+// Returns a tar archive of the entries
+func tarArchive(t *testing.T, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typ,
+			Linkname: entry.link,
+			Mode:     0644,
+			Size:     int64(len(entry.body)),
+			ModTime:  time.Unix(1500000000, 0),
+		}
+		if entry.typ == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if entry.mode != 0 {
+			header.Mode = entry.mode
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Returns a zip archive of the entries, hard links are not supported by zip
+func zipArchive(t *testing.T, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		body := entry.body
+		switch entry.typ {
+		case tar.TypeDir:
+			header.SetMode(os.ModeDir | 0755)
+		case tar.TypeSymlink:
+			header.SetMode(os.ModeSymlink | 0777)
+			body = entry.link
+		default:
+			header.SetMode(0644)
+		}
+		if entry.mode != 0 {
+			header.SetMode(header.Mode()&os.ModeType | os.FileMode(entry.mode))
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Returns an archive of testEntries in the format
+func testArchive(t *testing.T, format Format) []byte {
+	if format == ZIP {
+		return zipArchive(t, testEntries)
+	}
+	if format == TAR_BZ2 {
+		archive, err := base64.StdEncoding.DecodeString(bz2Archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return archive
+	}
+
+	archive := tarArchive(t, testEntries)
+	var buf bytes.Buffer
+	switch format {
+	case TAR:
+		return archive
+	case TAR_GZ:
+		w := gzip.NewWriter(&buf)
+		w.Write(archive)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case TAR_XZ:
+		w, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(archive)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case TAR_ZST:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(archive)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+var formats = []Format{TAR, TAR_GZ, TAR_XZ, TAR_ZST, TAR_BZ2, ZIP}
+
+func TestDetectFormat(t *testing.T) {
+	for _, format := range formats {
+		detected, err := DetectFormat(bytes.NewReader(testArchive(t, format)))
+		if err != nil {
+			t.Errorf("%s : %v", format, err)
+		} else if detected != format {
+			t.Errorf("detected %s for a %s archive", detected, format)
+		}
+	}
+
+	for _, content := range []string{"", "PK", "not an archive", string(make([]byte, 1024))} {
+		if format, err := DetectFormat(bytes.NewReader([]byte(content))); err == nil {
+			t.Errorf("detected %s for %q", format, content)
+		}
+	}
+}
+
+func TestExtractFormats(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm-extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, format := range formats {
+		source := filepath.Join(root, "go1.9."+string(format))
+		if err := ioutil.WriteFile(source, testArchive(t, format), 0644); err != nil {
+			t.Fatal(err)
+		}
+		destination := filepath.Join(root, string(format))
+		if _, err := Extract(context.Background(), source, destination, Options{StripComponents: 1}); err != nil {
+			t.Errorf("%s : %v", format, err)
+			continue
+		}
+
+		for name, expected := range map[string]string{"bin/go": "binary", "VERSION": "go1.9"} {
+			content, err := ioutil.ReadFile(filepath.Join(destination, name))
+			if err != nil {
+				t.Errorf("%s : %v", format, err)
+			} else if string(content) != expected {
+				t.Errorf("%s : %s has content %q, expected %q", format, name, content, expected)
+			}
+		}
+	}
+}
+
+func TestExtractUnknownFormat(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm-extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	source := filepath.Join(root, "go1.9.tar.gz")
+	if err := ioutil.WriteFile(source, []byte("<html>Not Found</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	destination := filepath.Join(root, "go")
+	if _, err := Extract(context.Background(), source, destination, Options{}); err == nil {
+		t.Error("an archive of unknown format was extracted")
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Error("destination was created for an archive of unknown format")
+	}
+}
+
+var extractTests = []struct {
+	name    string
+	entries []testEntry
+	opts    Options
+	// Reports if the archive is to be refused as unsafe
+	unsafe bool
+}{
+	{
+		name: "valid archive",
+		entries: []testEntry{
+			dir("go/"),
+			dir("go/bin/"),
+			file("go/bin/go", "binary"),
+			symlink("go/bin/gofmt", "go"),
+			symlink("go/misc", "bin/../bin"),
+			hardlink("go/bin/go.link", "go/bin/go"),
+		},
+	},
+	{
+		name:    "zip slip",
+		entries: []testEntry{file("go/../../evil", "evil")},
+		unsafe:  true,
+	},
+	{
+		name:    "zip slip after stripping",
+		entries: []testEntry{file("go/../evil", "evil")},
+		opts:    Options{StripComponents: 1},
+		unsafe:  true,
+	},
+	{
+		name:    "absolute path",
+		entries: []testEntry{file("/tmp/evil", "evil")},
+		unsafe:  true,
+	},
+	{
+		name:    "absolute symbolic link target",
+		entries: []testEntry{symlink("passwd", "/etc/passwd")},
+		unsafe:  true,
+	},
+	{
+		name:    "symbolic link escaping the destination",
+		entries: []testEntry{symlink("go/up", "../../secret")},
+		unsafe:  true,
+	},
+	{
+		name: "chained symbolic links",
+		entries: []testEntry{
+			symlink("deep/deeper/up", "../.."),
+			symlink("t", "deep/deeper/up/../secret"),
+		},
+		unsafe: true,
+	},
+	{
+		name: "symbolic link through a later symbolic link",
+		entries: []testEntry{
+			symlink("a", "d/b/../../secret"),
+			symlink("d/b", ".."),
+		},
+		unsafe: true,
+	},
+	{
+		name: "symbolic link loop",
+		entries: []testEntry{
+			symlink("a", "b/x"),
+			symlink("b", "a/x"),
+		},
+		unsafe: true,
+	},
+	{
+		name: "file written through a symbolic link",
+		entries: []testEntry{
+			dir("sub/"),
+			symlink("link", "sub"),
+			file("link/evil", "evil"),
+		},
+		unsafe: true,
+	},
+	{
+		name: "hard link escaping the destination",
+		entries: []testEntry{
+			hardlink("passwd", "../../etc/passwd"),
+		},
+		unsafe: true,
+	},
+	{
+		name: "hard link to an absolute path",
+		entries: []testEntry{
+			hardlink("passwd", "/etc/passwd"),
+		},
+		unsafe: true,
+	},
+	{
+		name: "hard link through a symbolic link",
+		entries: []testEntry{
+			symlink("root", "."),
+			file("secret", "secret"),
+			hardlink("link", "root/secret"),
+		},
+		unsafe: true,
+	},
+	{
+		name: "size limit",
+		entries: []testEntry{
+			file("a", "0123456789"),
+			file("b", "0123456789"),
+		},
+		opts:   Options{MaxSize: 15},
+		unsafe: true,
+	},
+	{
+		name: "size within the limit",
+		entries: []testEntry{
+			file("a", "0123456789"),
+			file("b", "0123456789"),
+		},
+		opts: Options{MaxSize: 20},
+	},
+	{
+		name: "file limit",
+		entries: []testEntry{
+			dir("go/"),
+			file("go/a", "a"),
+			file("go/b", "b"),
+		},
+		opts:   Options{MaxFiles: 2},
+		unsafe: true,
+	},
+}
+
+func TestExtract(t *testing.T) {
+	for _, test := range extractTests {
+		for _, format := range []Format{TAR, ZIP} {
+			if format == ZIP && hasHardlinks(test.entries) {
+				continue
+			}
+			t.Run(test.name+" "+string(format), func(t *testing.T) {
+				var archive []byte
+				if format == ZIP {
+					archive = zipArchive(t, test.entries)
+				} else {
+					archive = tarArchive(t, test.entries)
+				}
+				checkExtract(t, archive, test.opts, test.unsafe)
+			})
+		}
+	}
+}
+
+func hasHardlinks(entries []testEntry) bool {
+	for _, entry := range entries {
+		if entry.typ == tar.TypeLink {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract the archive to a destination nested in a temporary directory, checking
+// the error and that nothing was created next to the destination.
+func checkExtract(t *testing.T, archive []byte, opts Options, unsafe bool) {
+	root, err := ioutil.TempDir("", "gvm-extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	source := filepath.Join(root, "archive")
+	if err := ioutil.WriteFile(source, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	destination := filepath.Join(root, "a", "b", "destination")
+
+	_, err = Extract(context.Background(), source, destination, opts)
+	if unsafe {
+		if _, ok := err.(*UnsafeArchiveError); !ok {
+			t.Errorf("expected an unsafe archive error, got %v", err)
+		}
+	} else if err != nil {
+		t.Errorf("extraction failed : %v", err)
+	}
+
+	// Only the destination is created in its parent directories
+	for dir, expected := range map[string]string{
+		root:                       "a",
+		filepath.Join(root, "a"):   "b",
+		filepath.Join(root, "a/b"): "destination",
+	} {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, info := range infos {
+			if info.Name() != expected && info.Name() != "archive" {
+				t.Errorf("%s was created outside of the destination", filepath.Join(dir, info.Name()))
+			}
+		}
+	}
+}
+
+func TestExtractValidArchive(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm-extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	source := filepath.Join(root, "go1.9.tar")
+	if err := ioutil.WriteFile(source, tarArchive(t, extractTests[0].entries), 0644); err != nil {
+		t.Fatal(err)
+	}
+	destination := filepath.Join(root, "go")
+	summary, err := Extract(context.Background(), source, destination, Options{StripComponents: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Files != 1 || summary.Dirs != 1 || summary.Symlinks != 2 || summary.Links != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	for _, name := range []string{"bin/go", "bin/gofmt", "bin/go.link", "misc/go"} {
+		content, err := ioutil.ReadFile(filepath.Join(destination, name))
+		if err != nil {
+			t.Error(err)
+		} else if string(content) != "binary" {
+			t.Errorf("%s has content %q", name, content)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(destination, "bin/go")); err != nil || !info.ModTime().Equal(time.Unix(1500000000, 0)) {
+		t.Errorf("modification time of bin/go was not preserved")
+	}
+}
+
+func TestExtractAttributes(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm-extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	readOnlyDir := dir("go/lib/")
+	readOnlyDir.mode = 0555
+	executable := file("go/bin/go", "binary")
+	executable.mode = 0755
+	fifo := testEntry{name: "go/fifo", typ: tar.TypeFifo}
+	entries := []testEntry{
+		dir("go/"),
+		dir("go/bin/"),
+		executable,
+		symlink("go/bin/gofmt", "go"),
+		hardlink("go/bin/go.link", "go/bin/go"),
+		readOnlyDir,
+		file("go/lib/time.zip", "zoneinfo"),
+		fifo,
+	}
+	for _, format := range []Format{TAR, ZIP} {
+		archive := tarArchive(t, entries)
+		if format == ZIP {
+			archive = zipArchive(t, entries[:4])
+		}
+		source := filepath.Join(root, "go1.9."+string(format))
+		if err := ioutil.WriteFile(source, archive, 0644); err != nil {
+			t.Fatal(err)
+		}
+		destination := filepath.Join(root, string(format))
+		summary, err := Extract(context.Background(), source, destination, Options{StripComponents: 1})
+		// Let the read only directory be removed
+		defer os.Chmod(filepath.Join(destination, "lib"), 0755)
+		if err != nil {
+			t.Fatalf("%s : %v", format, err)
+		}
+
+		checkMode(t, filepath.Join(destination, "bin/go"), 0755)
+		if target, err := os.Readlink(filepath.Join(destination, "bin/gofmt")); err != nil || target != "go" {
+			t.Errorf("%s : bin/gofmt links to %q, expected go", format, target)
+		}
+		if format == ZIP {
+			if summary.Files != 1 || summary.Dirs != 1 || summary.Symlinks != 1 {
+				t.Errorf("%s : unexpected summary %+v", format, summary)
+			}
+			continue
+		}
+
+		if summary.Files != 2 || summary.Dirs != 2 || summary.Symlinks != 1 || summary.Links != 1 {
+			t.Errorf("%s : unexpected summary %+v", format, summary)
+		}
+		if len(summary.Skipped) != 1 || summary.Skipped[0].Name != "go/fifo" {
+			t.Errorf("%s : unexpected skipped entries %+v", format, summary.Skipped)
+		}
+		if content, err := ioutil.ReadFile(filepath.Join(destination, "bin/go.link")); err != nil || string(content) != "binary" {
+			t.Errorf("%s : bin/go.link has content %q", format, content)
+		}
+		checkMode(t, filepath.Join(destination, "lib"), os.ModeDir|0555)
+		for _, name := range []string{"bin", "bin/go", "lib", "lib/time.zip"} {
+			info, err := os.Stat(filepath.Join(destination, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(time.Unix(1500000000, 0)) {
+				t.Errorf("%s : modification time of %s was not preserved", format, name)
+			}
+		}
+	}
+}
+
+func checkMode(t *testing.T, path string, expected os.FileMode) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != expected {
+		t.Errorf("%s has mode %s, expected %s", path, info.Mode(), expected)
+	}
+}
@@ -0,0 +1,555 @@
+package gvm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fristonio/gvm/extract"
+	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/manager"
+	"github.com/fristonio/gvm/network"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Default number of connections used for downloading an archive
+const DEFAULT_CONNECTIONS = 4
+
+// Options for installing a go version
+type InstallOptions struct {
+	// Install the official binary distribution instead of compiling from source
+	Binary bool
+	// Locations of mirrors of the release archives, tried in order until the
+	// archive can be downloaded from one of them. The releases are looked up
+	// from the release source of the manager when empty.
+	Mirrors []string
+	// Patch files to apply to the source before compilation, in addition to the
+	// ones in the patches directory of the release.
+	Patches []string
+	// Options for the compilation of the source, when zero the options recorded
+	// for a previous install of the version are reused.
+	Build manager.BuildOptions
+	// Options for the compilation when Build is zero and no options were
+	// recorded for the version.
+	DefaultBuild manager.BuildOptions
+	// Run the go test suite after the compilation and record its result
+	RunTests bool
+	// Number of connections used for downloading the archive, DEFAULT_CONNECTIONS when 0
+	Connections int
+	// Number of times a failing part of the download is retried
+	Retries int
+	// Maximum download rate in bytes per second, 0 for no limit
+	LimitRate int64
+	// Stream the archive into the installation without keeping it in the downloads directory
+	NoCache bool
+	// Remove the archive from the downloads directory once the install succeeded
+	RemoveArchive bool
+	// Maximum total size of the extracted files, 0 for no limit
+	MaxExtractSize int64
+	// Maximum number of entries extracted from the archive, 0 for no limit
+	MaxExtractFiles int
+	// Hide the output of the compilation and the test suite
+	Quiet bool
+	// Called when a previous download of the archive is found in the downloads
+	// directory, it is cleared if this returns true. The install is aborted when
+	// it returns false or is nil.
+	ClearDownload func(archive string) bool
+}
+
+// Install the go version, which is either the name of a release or a named variant
+// of it like go1.9@variant getting its own installation and environment. Returns
+// the metadata recorded for the installation.
+// Once ctx is done the install stops, removing the partial download, the staging
+// directory and the partially built installation, and an aborted error is returned.
+// Installing a version which the manager is already installing fails.
+func (m *Manager) Install(ctx context.Context, name string, opts InstallOptions) (*manager.Metadata, error) {
+	if !utils.IsValidGoName(name) {
+		return nil, invalidVersionError(name)
+	}
+	if opts.Binary && (!opts.Build.IsZero() || len(opts.Patches) > 0) {
+		return nil, utils.NewError(utils.USAGE_ERROR, "Build options and patches can not be used for binary installs")
+	}
+	if !m.startInstall(name) {
+		return nil, utils.NewError(utils.GENERIC_ERROR, "%s is already being installed", name)
+	}
+	defer m.finishInstall(name)
+
+	meta, err := m.install(ctx, name, opts)
+	if err != nil && ctx.Err() != nil {
+		return nil, utils.WrapError(utils.ABORTED_ERROR, ctx.Err(), "Install of "+name+" interrupted")
+	}
+	return meta, err
+}
+
+// Mark the version as being installed, reports false if it already is
+func (m *Manager) startInstall(name string) bool {
+	m.installingMu.Lock()
+	defer m.installingMu.Unlock()
+	if m.installing[name] {
+		return false
+	}
+	m.installing[name] = true
+	return true
+}
+
+func (m *Manager) finishInstall(name string) {
+	m.installingMu.Lock()
+	defer m.installingMu.Unlock()
+	delete(m.installing, name)
+}
+
+// Archive of the release to install, downloaded to the downloads directory or
+// streamed into the installation when stream is set.
+type archive struct {
+	release network.Release
+	stream  *network.Stream
+	// Path of the archive in the downloads directory, empty when it is streamed
+	path string
+	// Reports if the archive was already in the downloads directory
+	cached bool
+	// Sha256 the archive must have and where it comes from, sha256 is empty when
+	// there is nothing to check the archive against.
+	sha256       string
+	sha256Origin string
+}
+
+// Run the steps of the install
+func (m *Manager) install(ctx context.Context, name string, opts InstallOptions) (*manager.Metadata, error) {
+	releaseName, _ := utils.SplitGoName(name)
+	releases, err := m.locations(ctx, releaseName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var a *archive
+	if opts.NoCache {
+		a, err = m.openStream(ctx, releases, opts)
+	} else {
+		a, err = m.download(ctx, releases, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.stream != nil {
+		defer a.stream.Close()
+	}
+	if err = m.expectedSha256(ctx, a, name, opts); err != nil {
+		return nil, err
+	}
+
+	var meta *manager.Metadata
+	if opts.Binary {
+		meta, err = m.installBinary(ctx, a, name, opts)
+	} else {
+		meta, err = m.installSource(ctx, a, name, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if a.stream == nil && opts.RemoveArchive {
+		if err = os.Remove(a.path); err != nil {
+			m.log.Warnf("Could not remove the archive %s : %v", a.path, err)
+		}
+	}
+
+	if opts.RunTests {
+		if _, err = m.selfTest(ctx, meta, opts.Quiet); err != nil {
+			return meta, err
+		}
+	}
+	return meta, nil
+}
+
+// Returns the locations of the archive of the release, one for each mirror when
+// mirrors are configured, in which case the available releases are not looked up.
+func (m *Manager) locations(ctx context.Context, releaseName string, opts InstallOptions) ([]network.Release, error) {
+	archiveName := network.SourceArchiveName(releaseName)
+	if opts.Binary {
+		archiveName = network.BinaryArchiveName(releaseName)
+	}
+
+	if len(opts.Mirrors) > 0 {
+		m.log.Infof("Using mirror %s", strings.Join(opts.Mirrors, ", "))
+		releases := make([]network.Release, 0, len(opts.Mirrors))
+		for _, mirror := range opts.Mirrors {
+			releases = append(releases, network.Release{
+				Name:        releaseName,
+				DownloadUrl: network.MirrorLocation(mirror, archiveName),
+			})
+		}
+		return releases, nil
+	}
+	if opts.Binary {
+		return []network.Release{{
+			Name:        releaseName,
+			DownloadUrl: fmt.Sprintf(network.BINARY_DOWNLOAD_URL, archiveName),
+		}}, nil
+	}
+
+	releases, err := m.ListRemote(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, release := range releases {
+		if release.Name == releaseName {
+			return []network.Release{release}, nil
+		}
+	}
+	return nil, utils.NewError(utils.NOT_FOUND_ERROR, `Could not find a matching go version source.
+	Use gvm list-remote to list all the available versions.`)
+}
+
+// Reports if the archive is to be fetched from the next location after the error,
+// which is when it is missing or could not be downloaded from the location.
+func (m *Manager) tryNext(ctx context.Context, err error, next int, releases []network.Release) bool {
+	if ctx.Err() != nil || next >= len(releases) {
+		return false
+	}
+	kind := utils.KindOf(err)
+	if kind != utils.NOT_FOUND_ERROR && kind != utils.NETWORK_ERROR {
+		return false
+	}
+	m.log.Warnf("%v, trying %s", err, releases[next].DownloadUrl)
+	return true
+}
+
+// Returns the options for downloading the archive of the release
+func (m *Manager) downloadOptions(opts InstallOptions) network.DownloadOptions {
+	conn := opts.Connections
+	if conn <= 0 {
+		conn = DEFAULT_CONNECTIONS
+	}
+	return network.DownloadOptions{
+		Client:    m.client,
+		Conn:      int64(conn),
+		Retries:   opts.Retries,
+		LimitRate: opts.LimitRate,
+		Dir:       m.paths.Join(utils.GVM_DOWNLOAD_DIR),
+		Log:       m.log,
+	}
+}
+
+// Download the archive from the first of the locations it can be downloaded from
+func (m *Manager) download(ctx context.Context, releases []network.Release, opts InstallOptions) (*archive, error) {
+	var err error
+	for i, release := range releases {
+		var cached bool
+		if cached, err = m.downloadRelease(ctx, release, opts); err == nil {
+			path := m.paths.Join(utils.GVM_DOWNLOAD_DIR, filepath.Base(release.DownloadUrl))
+			return &archive{release: release, path: path, cached: cached}, nil
+		}
+		if !m.tryNext(ctx, err, i+1, releases) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// Download the archive of the release to the downloads directory unless it is already
+// there, reports if it was.
+func (m *Manager) downloadRelease(ctx context.Context, release network.Release, opts InstallOptions) (bool, error) {
+	archiveName := filepath.Base(release.DownloadUrl)
+	if utils.CheckIfAlreadyExist(m.paths.Join(utils.GVM_DOWNLOAD_DIR, archiveName)) {
+		m.log.Infof("Found a cached copy for %s", release.Name)
+		return true, nil
+	}
+
+	m.log.WithFields(logger.Fields{
+		"version": release.Name,
+		"url":     release.DownloadUrl,
+	}).Infof("Beggining to download source for %s", release.Name)
+	downloadOpts := m.downloadOptions(opts)
+	err := network.Download(ctx, release.DownloadUrl, downloadOpts)
+	if err == network.ErrPreviousDownload {
+		if opts.ClearDownload == nil || !opts.ClearDownload(archiveName) {
+			return false, utils.NewError(utils.ABORTED_ERROR, "Download aborted, the previous download was kept")
+		}
+		downloadOpts.ForceClean = true
+		err = network.Download(ctx, release.DownloadUrl, downloadOpts)
+	}
+	if err != nil {
+		return false, utils.WrapError(utils.NETWORK_ERROR, err, "An error occured while downloading go from source")
+	}
+	m.log.Info("Download completed...")
+	return false, nil
+}
+
+// Open the archive over a single connection from the first of the locations it can
+// be requested from, it is extracted while it is downloaded.
+func (m *Manager) openStream(ctx context.Context, releases []network.Release, opts InstallOptions) (*archive, error) {
+	var err error
+	for i, release := range releases {
+		m.log.WithFields(logger.Fields{
+			"version": release.Name,
+			"url":     release.DownloadUrl,
+		}).Infof("Streaming the archive of %s without caching it", release.Name)
+		stream, e := network.OpenStream(ctx, release.DownloadUrl, m.downloadOptions(opts))
+		if e == nil {
+			return &archive{release: release, stream: stream}, nil
+		}
+		err = utils.WrapError(utils.NETWORK_ERROR, e, "An error occured while requesting the archive")
+		if !m.tryNext(ctx, err, i+1, releases) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// Set the sha256 the archive must have, which is the published one for an official
+// binary distribution. A cached archive must also still have the sha256 recorded
+// when the version was installed from it, so that an archive replaced or corrupted
+// since then is not installed.
+func (m *Manager) expectedSha256(ctx context.Context, a *archive, name string, opts InstallOptions) error {
+	if opts.Binary && len(opts.Mirrors) == 0 {
+		sum, err := network.PublishedSha256(ctx, m.client, a.release.DownloadUrl)
+		if err == nil {
+			a.sha256, a.sha256Origin = sum, "published"
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.log.Warnf("Could not fetch the published sha256 of the archive : %v", err)
+	}
+
+	if !a.cached {
+		return nil
+	}
+	mode := manager.SOURCE_INSTALL
+	if opts.Binary {
+		mode = manager.BINARY_INSTALL
+	}
+	if meta, err := manager.ReadMetadata(m.paths, name); err == nil && meta.Mode == mode && meta.ArchiveSha256 != "" {
+		a.sha256, a.sha256Origin = meta.ArchiveSha256, "recorded for the install of "+name
+	}
+	return nil
+}
+
+// Returns the error for an archive whose sha256 is not the expected one
+func (a *archive) checksumError(sum string) error {
+	archiveName := filepath.Base(a.release.DownloadUrl)
+	hint := ""
+	if a.stream == nil {
+		hint = ", remove " + a.path + " to download it again"
+	}
+	return utils.NewError(utils.CHECKSUM_ERROR, "Sha256 of %s is %s but %s is %s%s",
+		archiveName, sum, a.sha256Origin, a.sha256, hint)
+}
+
+// Extract the source of the release, patch it and compile it. Returns the metadata
+// recorded for the installation, which is also recorded when the compilation fails.
+// An interrupted compilation removes the source tree instead.
+func (m *Manager) installSource(ctx context.Context, a *archive, name string, opts InstallOptions) (*manager.Metadata, error) {
+	buildOpts, err := m.buildOptions(name, opts.Build, opts.DefaultBuild)
+	if err != nil {
+		return nil, err
+	}
+	archiveSum, patches, err := m.prepareSource(ctx, a, name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the source of go obtained
+	m.log.Info("Compiling go from source")
+	start := time.Now()
+	compileErr := manager.CompileGoRelease(ctx, m.paths, m.log, name, buildOpts, opts.Quiet)
+	if ctx.Err() != nil {
+		m.log.Warnf("Compilation of %s was interrupted, removing its source", name)
+		os.RemoveAll(m.paths.Join(utils.GVM_GOS_DIRNAME, name))
+		return nil, ctx.Err()
+	}
+	if compileErr == nil {
+		m.log.WithFields(logger.Fields{
+			"version":  name,
+			"duration": time.Since(start).String(),
+		}).Infof("Compiled %s", name)
+	}
+	manager.CreateEnvironmentFile(m.paths, m.log, name)
+
+	meta := &manager.Metadata{
+		Name:          name,
+		Mode:          manager.SOURCE_INSTALL,
+		InstalledAt:   time.Now(),
+		ArchiveSha256: archiveSum,
+		Patches:       patches,
+		Build:         buildOpts,
+		Arch:          runtime.GOARCH,
+	}
+	if err := manager.WriteMetadata(m.paths, meta); err != nil {
+		m.log.Warnf("Could not record metadata for %s : %v", name, err)
+	}
+
+	if compileErr != nil {
+		return nil, utils.WrapError(utils.BUILD_ERROR, compileErr, "Error during compilation")
+	}
+	return meta, nil
+}
+
+// Extract the source of the release and apply the patches to it, returning the sha256
+// of the archive and the applied patches. If any step fails the extracted source is
+// removed so no half patched tree is left behind.
+func (m *Manager) prepareSource(ctx context.Context, a *archive, name string, opts InstallOptions) (archiveSum string, patches []manager.AppliedPatch, err error) {
+	archiveSum, err = m.extract(ctx, a, name, 0, opts)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(m.paths.Join(utils.GVM_GOS_DIRNAME, name))
+		}
+	}()
+
+	patches, err = m.applyPatches(name, opts.Patches)
+	return
+}
+
+// Extract the official binary distribution of the release, which is rooted at go/
+// so the leading directory is stripped. Returns the metadata recorded for it.
+func (m *Manager) installBinary(ctx context.Context, a *archive, name string, opts InstallOptions) (*manager.Metadata, error) {
+	archiveSum, err := m.extract(ctx, a, name, 1, opts)
+	if err != nil {
+		return nil, err
+	}
+	manager.CreateEnvironmentFile(m.paths, m.log, name)
+
+	meta := &manager.Metadata{
+		Name:          name,
+		Mode:          manager.BINARY_INSTALL,
+		InstalledAt:   time.Now(),
+		ArchiveSha256: archiveSum,
+		Arch:          runtime.GOARCH,
+	}
+	if err := manager.WriteMetadata(m.paths, meta); err != nil {
+		m.log.Warnf("Could not record metadata for %s : %v", name, err)
+	}
+	return meta, nil
+}
+
+// Extract the archive of the release to the gos directory, returning the sha256 of
+// the archive. The archive is extracted to a staging directory which replaces the
+// installation only once the extraction succeeds, a streamed archive is extracted
+// while it is downloaded and its sha256 computed on the fly.
+func (m *Manager) extract(ctx context.Context, a *archive, name string, strip int, opts InstallOptions) (string, error) {
+	gosDir := m.paths.Join(utils.GVM_GOS_DIRNAME)
+	destination := filepath.Join(gosDir, name)
+	staging := filepath.Join(gosDir, "."+name+".staging")
+	extractOpts := extract.Options{
+		StripComponents: strip,
+		MaxSize:         opts.MaxExtractSize,
+		MaxFiles:        opts.MaxExtractFiles,
+	}
+	// Do not leave a partially extracted tree behind, the staging directory is
+	// already moved to the destination once the extraction succeeded.
+	defer os.RemoveAll(staging)
+
+	var summary *extract.Summary
+	var sum string
+	var err error
+	if a.stream != nil {
+		summary, err = extract.ExtractReader(ctx, a.stream, staging, extractOpts)
+		if err == nil {
+			err = a.stream.Drain()
+		}
+		// The archive is only checked once it is streamed, the staging directory
+		// is removed if it does not match.
+		sum = a.stream.Sha256()
+		if err == nil && a.sha256 != "" && sum != a.sha256 {
+			return "", a.checksumError(sum)
+		}
+	} else {
+		if sum, err = utils.FileSha256(a.path); err != nil {
+			return "", err
+		}
+		if a.sha256 != "" && sum != a.sha256 {
+			return "", a.checksumError(sum)
+		}
+		m.log.Info("Unzipping the downloaded archive ...")
+		summary, err = extract.Extract(ctx, a.path, staging, extractOpts)
+	}
+	if unsafeErr, ok := err.(*extract.UnsafeArchiveError); ok {
+		m.log.Error("The archive may be corrupted or malicious, check its source before retrying")
+		return "", utils.WrapError(utils.CHECKSUM_ERROR, unsafeErr,
+			"Refusing to extract "+filepath.Base(a.release.DownloadUrl))
+	}
+	if a.stream != nil && a.stream.Err() != nil {
+		// The archive could not be extracted because its download failed
+		return "", utils.WrapError(utils.NETWORK_ERROR, a.stream.Err(),
+			"Error while downloading "+a.release.DownloadUrl)
+	}
+	if err != nil {
+		return "", utils.WrapError(utils.GENERIC_ERROR, err, "Error while trying to decompress archive")
+	}
+	if err = os.RemoveAll(destination); err != nil {
+		return "", err
+	}
+	if err = os.Rename(staging, destination); err != nil {
+		return "", err
+	}
+
+	m.log.WithFields(logger.Fields{
+		"version": name,
+		"bytes":   summary.Size,
+	}).Infof("Extracted %d files, %d directories and %d links",
+		summary.Files, summary.Dirs, summary.Symlinks+summary.Links)
+	if len(summary.Skipped) > 0 {
+		m.log.Warnf("Skipped %d entries of the archive :", len(summary.Skipped))
+		for _, entry := range summary.Skipped {
+			m.log.Warnf("    %s : %s", entry.Name, entry.Reason)
+		}
+	}
+	m.log.WithFields(logger.Fields{
+		"version": name,
+		"sha256":  sum,
+	}).Infof("Sha256 of the archive : %s", sum)
+	return sum, nil
+}
+
+// Apply the patches for the release to the extracted source
+func (m *Manager) applyPatches(name string, extra []string) ([]manager.AppliedPatch, error) {
+	patches, err := manager.CollectPatches(m.paths, name, extra)
+	if err != nil {
+		return nil, utils.WrapError(utils.NOT_FOUND_ERROR, err, "Error while collecting patches")
+	}
+	if len(patches) == 0 {
+		return nil, nil
+	}
+
+	m.log.Infof("Applying %d patches to the source", len(patches))
+	applied, err := manager.ApplyPatches(m.paths, name, patches)
+	if err != nil {
+		return nil, utils.WrapError(utils.BUILD_ERROR, err, "Error while patching the source")
+	}
+	for _, p := range applied {
+		m.log.Logf("Applied patch %s", p.Name)
+	}
+	return applied, nil
+}
+
+// Returns the build options for the installation. When no option is provided and
+// the version is already installed, the options recorded for it are reused so
+// that a reinstall reproduces the previous build, otherwise the defaults are used.
+func (m *Manager) buildOptions(name string, opts manager.BuildOptions, defaults manager.BuildOptions) (manager.BuildOptions, error) {
+	if opts.IsZero() {
+		if meta, err := manager.ReadMetadata(m.paths, name); err == nil && !meta.Build.IsZero() {
+			m.log.Infof("Reusing build options recorded for %s", name)
+			opts = meta.Build
+			if opts.MicroArch != "" && meta.Arch != runtime.GOARCH {
+				m.log.Warnf("Not reusing the microarchitecture level %s recorded for %s on %s",
+					opts.MicroArch, name, runtime.GOARCH)
+				opts.MicroArch = ""
+			}
+		} else {
+			opts = defaults
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return opts, utils.WrapError(utils.USAGE_ERROR, err, "Invalid build options")
+	}
+	return opts, nil
+}
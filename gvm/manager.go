@@ -0,0 +1,307 @@
+// Package gvm manages go toolchains under a gvm root directory, it is the library
+// the gvm command line is built on and can be embedded by other programs to list,
+// install and select go versions.
+package gvm
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/manager"
+	"github.com/fristonio/gvm/network"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Configuration of a Manager, the zero value of each field selects its default
+type Config struct {
+	// Root directory of the installations, defaults to ~/.gvm
+	Root string
+	// Logger for the progress of the operations, defaults to the shared logger.Log
+	Log *logger.Logger
+	// Client for the requests, defaults to a client from network.NewClient
+	Client *http.Client
+	// Source of the releases available to install, defaults to the tags of the
+	// go repository requested with the client.
+	Releases network.ReleaseSource
+	// Name of the package set of the environments, defaults to global
+	Pkgset string
+}
+
+// Manager of the go versions installed under a root directory
+type Manager struct {
+	paths    utils.Paths
+	log      *logger.Logger
+	client   *http.Client
+	releases network.ReleaseSource
+	// Names of the versions being installed, the installs of different versions
+	// run concurrently while a version is only installed once at a time.
+	installingMu sync.Mutex
+	installing   map[string]bool
+}
+
+// Installed go version
+type Installation struct {
+	Name string
+	// Directory of the installation in the gos directory
+	Path string
+	// Time the version was installed, the modification time of its directory
+	// when it was not recorded in the metadata.
+	InstalledAt time.Time
+	// Names of the package sets of the version
+	Pkgsets []string
+	// Reports if this is the default version
+	Default bool
+	// Reports if this is the version of the environment gvm is run from
+	Current bool
+	// Metadata recorded for the installation
+	Meta *manager.Metadata
+}
+
+// Status of a release of go in the root of a manager
+type ReleaseStatus struct {
+	// Reports if the release or a variant of it is installed
+	Installed bool
+	// Reports if an archive of the release is kept in the downloads directory
+	Cached bool
+}
+
+// Returns a new manager for the configuration
+func NewManager(config Config) *Manager {
+	m := &Manager{
+		paths:      utils.Paths{Root: config.Root, Pkgset: config.Pkgset},
+		log:        config.Log,
+		client:     config.Client,
+		releases:   config.Releases,
+		installing: make(map[string]bool),
+	}
+	if m.paths.Root == "" {
+		m.paths.Root = filepath.Join(os.Getenv("HOME"), ".gvm")
+	}
+	if m.paths.Pkgset == "" {
+		m.paths.Pkgset = "global"
+	}
+	if m.log == nil {
+		m.log = logger.Log
+	}
+	if m.client == nil {
+		m.client = network.NewClient(false)
+	}
+	if m.releases == nil {
+		m.releases = network.NewTagsSource(m.client)
+	}
+	return m
+}
+
+// Returns the root directory of the installations
+func (m *Manager) Root() string {
+	return m.paths.Root
+}
+
+// Returns the error for an invalid name of an installation
+func invalidVersionError(name string) error {
+	return utils.NewError(utils.USAGE_ERROR,
+		"%s is not a valid go version, it should be of the format : goX.X or goX.X@variant", name)
+}
+
+// Returns the error for a version which is not installed
+func notInstalledError(name string) error {
+	return utils.NewError(utils.NOT_FOUND_ERROR,
+		"%s is not installed, to view a list of installed versions use: gvm list", name)
+}
+
+// Check that the name is valid and that it is installed in the root of the manager
+func (m *Manager) checkInstalled(name string) error {
+	if !utils.IsValidGoName(name) {
+		return invalidVersionError(name)
+	}
+	if !utils.CheckIfAlreadyExist(m.paths.Join(utils.GVM_GOS_DIRNAME, name)) {
+		return notInstalledError(name)
+	}
+	return nil
+}
+
+// Returns the releases of go available to install
+func (m *Manager) ListRemote(ctx context.Context) ([]network.Release, error) {
+	releases, err := m.releases.Releases(ctx)
+	if ctx.Err() != nil {
+		return nil, utils.WrapError(utils.ABORTED_ERROR, ctx.Err(), "Listing of the releases interrupted")
+	}
+	if err != nil {
+		return nil, utils.WrapError(utils.NETWORK_ERROR, err, "An error occured while parsing available releases")
+	}
+	return releases, nil
+}
+
+// Returns the installed go versions sorted by name, a not found error is returned
+// if nothing was ever installed.
+func (m *Manager) ListInstalled() ([]Installation, error) {
+
+	gos, err := ioutil.ReadDir(m.paths.Join(utils.GVM_GOS_DIRNAME))
+	if err != nil {
+		return nil, utils.NewError(utils.NOT_FOUND_ERROR,
+			"No gos installed, to view a list of versions available use: gvm list-remote")
+	}
+
+	defaultName := manager.GetDefault(m.paths)
+	currentName := ""
+	if os.Getenv("GVM_ROOT") == m.paths.Root {
+		currentName = os.Getenv("GVM_GO_VERSION")
+	}
+	installations := make([]Installation, 0)
+	for _, f := range gos {
+		if !f.IsDir() || !utils.IsValidGoName(f.Name()) {
+			continue
+		}
+		meta, err := manager.ReadMetadata(m.paths, f.Name())
+		if err != nil {
+			m.log.Warnf("%v", err)
+		}
+		installedAt := meta.InstalledAt
+		if installedAt.IsZero() {
+			installedAt = f.ModTime()
+		}
+		path := m.paths.Join(utils.GVM_GOS_DIRNAME, f.Name())
+		installations = append(installations, Installation{
+			Name:        f.Name(),
+			Path:        path,
+			InstalledAt: installedAt,
+			Pkgsets:     m.pkgsets(f.Name()),
+			Default:     f.Name() == defaultName,
+			Current:     f.Name() == currentName,
+			Meta:        meta,
+		})
+	}
+	sort.Slice(installations, func(i, j int) bool {
+		return installations[i].Name < installations[j].Name
+	})
+	return installations, nil
+}
+
+// Returns the total size of the files of the installation in bytes, which walks
+// the whole installation and is only computed when asked for.
+func (i Installation) Size() int64 {
+	return dirSize(i.Path)
+}
+
+// Returns the total size of the regular files under the directory
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// Returns the names of the package sets of the version
+func (m *Manager) pkgsets(name string) []string {
+	names := make([]string, 0)
+	dirs, _ := ioutil.ReadDir(m.paths.Join(utils.GVM_PKGSET_DIRNAME, name))
+	for _, dir := range dirs {
+		if dir.IsDir() {
+			names = append(names, dir.Name())
+		}
+	}
+	return names
+}
+
+// Returns the status of each of the releases, whether they are installed and
+// whether their source or binary archive is cached.
+func (m *Manager) ReleaseStatus(releases []network.Release) []ReleaseStatus {
+	installed := make(map[string]bool)
+	gos, _ := ioutil.ReadDir(m.paths.Join(utils.GVM_GOS_DIRNAME))
+	for _, f := range gos {
+		if f.IsDir() && utils.IsValidGoName(f.Name()) {
+			release, _ := utils.SplitGoName(f.Name())
+			installed[release] = true
+		}
+	}
+
+	downloads := m.paths.Join(utils.GVM_DOWNLOAD_DIR)
+	statuses := make([]ReleaseStatus, len(releases))
+	for i, release := range releases {
+		statuses[i] = ReleaseStatus{
+			Installed: installed[release.Name],
+			Cached: utils.CheckIfAlreadyExist(filepath.Join(downloads, network.SourceArchiveName(release.Name))) ||
+				utils.CheckIfAlreadyExist(filepath.Join(downloads, network.BinaryArchiveName(release.Name))),
+		}
+	}
+	return statuses
+}
+
+// Returns the variables of the environment of the installed version as NAME=value,
+// to be used as the environment of the commands run with it.
+func (m *Manager) Env(name string) ([]string, error) {
+	if err := m.checkInstalled(name); err != nil {
+		return nil, err
+	}
+	return manager.Environment(m.paths, name), nil
+}
+
+// Returns the path of the environment file of the installed version, which is
+// to be sourced by a shell to use the version.
+func (m *Manager) EnvFile(name string) (string, error) {
+	if !utils.IsValidGoName(name) {
+		return "", invalidVersionError(name)
+	}
+	envFile := m.paths.Join(utils.GVM_ENV_DIRNAME, name)
+	if !utils.CheckIfAlreadyExist(envFile) {
+		return "", notInstalledError(name)
+	}
+	return envFile, nil
+}
+
+// Mark the installed version as the default one, a version whose self test failed
+// is refused unless force is set.
+func (m *Manager) SetDefault(name string, force bool) error {
+	if err := m.checkInstalled(name); err != nil {
+		return err
+	}
+	return manager.SetDefault(m.paths, name, force)
+}
+
+// Remove the installation of the version along with its environment, package
+// sets and metadata.
+func (m *Manager) Uninstall(name string) error {
+	if err := m.checkInstalled(name); err != nil {
+		return err
+	}
+
+	if manager.GetDefault(m.paths) == name {
+		if err := manager.UnsetDefault(m.paths); err != nil {
+			return err
+		}
+	}
+
+	envFile := m.paths.Join(utils.GVM_ENV_DIRNAME, name)
+	if err := os.Remove(envFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, dir := range []string{utils.GVM_GOS_DIRNAME, utils.GVM_PKGSET_DIRNAME} {
+		if err := os.RemoveAll(m.paths.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return manager.RemoveMetadata(m.paths, name)
+}
+
+// Returns the path of the last log of the kind, compile or selftest, for the version
+func (m *Manager) LogFile(name string, kind string) (string, error) {
+	if !utils.IsValidGoName(name) {
+		return "", invalidVersionError(name)
+	}
+	logFile, err := manager.LastLogFile(m.paths, name, kind)
+	if err != nil {
+		return "", utils.WrapError(utils.NOT_FOUND_ERROR, err, "Error while looking up the log")
+	}
+	return logFile, nil
+}
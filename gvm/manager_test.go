@@ -0,0 +1,92 @@
+package gvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+func TestManagersOfDifferentRoots(t *testing.T) {
+	roots := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		root, err := ioutil.TempDir("", "gvm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(root)
+		// Each root has its own version installed
+		name := []string{"go1.9", "go1.10", "go1.9@a", "go1.10@b"}[i]
+		if err := os.MkdirAll(filepath.Join(root, utils.GVM_GOS_DIRNAME, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+
+	var wg sync.WaitGroup
+	for _, root := range roots {
+		wg.Add(1)
+		go func(root string) {
+			defer wg.Done()
+			m := NewManager(Config{Root: root, Pkgset: "pkgset"})
+			for j := 0; j < 20; j++ {
+				installations, err := m.ListInstalled()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(installations) != 1 || filepath.Dir(installations[0].Path) != filepath.Join(root, utils.GVM_GOS_DIRNAME) {
+					t.Errorf("manager of %s listed %+v", root, installations)
+					return
+				}
+				env, err := m.Env(installations[0].Name)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !contains(env, "GVM_ROOT="+root) || !contains(env, "GVM_PACKAGESET_NAME=pkgset") {
+					t.Errorf("manager of %s has the environment %v", root, env)
+					return
+				}
+			}
+		}(root)
+	}
+	wg.Wait()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInstallationSize(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	bin := filepath.Join(root, utils.GVM_GOS_DIRNAME, "go1.9", "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"go": 100, "gofmt": 20} {
+		if err := ioutil.WriteFile(filepath.Join(bin, name), make([]byte, size), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	installations, err := NewManager(Config{Root: root}).ListInstalled()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installations) != 1 || installations[0].Size() != 120 {
+		t.Errorf("unexpected installations %+v", installations)
+	}
+}
@@ -0,0 +1,57 @@
+package gvm
+
+import (
+	"context"
+
+	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/manager"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Run the go test suite for the installed version and record its result in the
+// metadata of the version, a build error is returned along with the result if
+// the test suite did not pass. The test suite is killed once ctx is done.
+func (m *Manager) SelfTest(ctx context.Context, name string, quiet bool) (*manager.SelfTestResult, error) {
+	if err := m.checkInstalled(name); err != nil {
+		return nil, err
+	}
+
+	meta, err := manager.ReadMetadata(m.paths, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.selfTest(ctx, meta, quiet)
+}
+
+// Run the self test for the installation described by meta and record its result
+func (m *Manager) selfTest(ctx context.Context, meta *manager.Metadata, quiet bool) (*manager.SelfTestResult, error) {
+	m.log.Infof("Running the test suite for %s", meta.Name)
+	result, err := manager.RunSelfTest(ctx, m.paths, m.log, meta.Name, meta.Build, quiet)
+	if ctx.Err() != nil {
+		return nil, utils.WrapError(utils.ABORTED_ERROR, ctx.Err(), "Test suite of "+meta.Name+" interrupted")
+	}
+	if err != nil {
+		return nil, utils.WrapError(utils.BUILD_ERROR, err, "Error while running the test suite")
+	}
+
+	meta.SelfTest = result
+	if err = manager.WriteMetadata(m.paths, meta); err != nil {
+		m.log.Warnf("Could not record metadata for %s : %v", meta.Name, err)
+	}
+
+	if !result.Passed {
+		m.log.WithFields(logger.Fields{
+			"version":  meta.Name,
+			"duration": result.Duration,
+		}).Errorf("Self test of %s failed after %s", meta.Name, result.Duration)
+		for _, failure := range result.Failures {
+			m.log.Error(failure)
+		}
+		return result, utils.NewError(utils.BUILD_ERROR, "Self test of %s failed", meta.Name)
+	}
+	m.log.WithFields(logger.Fields{
+		"version":  meta.Name,
+		"duration": result.Duration,
+	}).Infof("Self test of %s passed in %s", meta.Name, result.Duration)
+	return result, nil
+}
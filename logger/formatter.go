@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Formats of the output of the logger
+const (
+	TextFormat string = "text"
+	JSONFormat string = "json"
+)
+
+// Structured fields attached to a message
+type Fields map[string]interface{}
+
+// A message to be written by the logger
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  Fields
+	// Marker of the message in the text output, like [*] for info
+	tag string
+}
+
+// Formatter renders an entry as the bytes written to the output of the logger,
+// including the trailing newline.
+type Formatter interface {
+	Format(entry *Entry) ([]byte, error)
+}
+
+// Returns the formatter for the format, one of text or json
+func NewFormatter(format string) (Formatter, error) {
+	switch format {
+	case TextFormat:
+		return &TextFormatter{}, nil
+	case JSONFormat:
+		return &JSONFormatter{}, nil
+	}
+	return nil, fmt.Errorf("Unknown log format %q, it should be one of text or json", format)
+}
+
+// Colors of the text output for each level
+var levelColors = map[Level]string{
+	DebugLevel: Cyan,
+	InfoLevel:  Blue,
+	WarnLevel:  Yellow,
+	ErrorLevel: Red,
+}
+
+// Formats entries as human readable lines prefixed with the marker of the message,
+// the fields are not rendered as messages carry the same information for humans.
+type TextFormatter struct {
+	Color bool
+}
+
+func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
+	var b bytes.Buffer
+	if f.Color && entry.tag != "" {
+		b.WriteString(levelColors[entry.Level])
+	}
+	if entry.tag != "" {
+		b.WriteString(entry.tag + " ")
+	}
+	b.WriteString(entry.Message)
+	if f.Color && entry.tag != "" {
+		b.WriteString(Reset)
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// Formats entries as one JSON object per line with the time, level and message
+// of the entry along with its fields.
+type JSONFormatter struct{}
+
+func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(entry.Fields)+3)
+	for key, value := range entry.Fields {
+		// Errors are not marshalled to their message by encoding/json
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		data[key] = value
+	}
+	data["time"] = entry.Time.Format(time.RFC3339)
+	data["level"] = entry.Level.String()
+	data["message"] = entry.Message
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
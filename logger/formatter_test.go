@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out)
+	l.SetFormatter(&JSONFormatter{})
+
+	l.WithFields(Fields{"version": "go1.9", "parts": 4}).Info("Download started")
+	l.Debug("Not logged at info level")
+	l.WithField("error", errors.New("connection reset")).Errorf("Download failed\nafter %d retries", 5)
+
+	expected := []map[string]interface{}{
+		{"level": "info", "message": "Download started", "version": "go1.9", "parts": float64(4)},
+		{"level": "error", "message": "Download failed\nafter 5 retries", "error": "connection reset"},
+	}
+	scanner := bufio.NewScanner(&out)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		if i >= len(expected) {
+			t.Fatalf("unexpected line %s", scanner.Text())
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &object); err != nil {
+			t.Fatalf("line %q is not a JSON object : %v", scanner.Text(), err)
+		}
+
+		timestamp, ok := object["time"].(string)
+		if !ok {
+			t.Errorf("line %d has no time", i)
+		} else if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+			t.Errorf("line %d has an invalid time : %v", i, err)
+		}
+		delete(object, "time")
+		if len(object) != len(expected[i]) {
+			t.Errorf("line %d has keys %v, expected %v", i, object, expected[i])
+		}
+		for key, value := range expected[i] {
+			if object[key] != value {
+				t.Errorf("line %d has %s %v, expected %v", i, key, object[key], value)
+			}
+		}
+	}
+	if i != len(expected) {
+		t.Errorf("logged %d lines, expected %d", i, len(expected))
+	}
+}
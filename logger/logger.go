@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/shiena/ansicolor"
 )
@@ -21,96 +24,266 @@ const (
 	Reset   string = "\x1b[0m"
 )
 
+// Severity of a message, messages below the level of the logger are discarded
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Returns the level with the given name, one of debug, info, warn or error
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return WarnLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("Unknown log level %q, it should be one of debug, info, warn or error", name)
+}
+
+// Configuration of a logger, shared with the loggers derived from it
+type core struct {
+	out       io.Writer // destination for output
+	level     Level
+	color     bool
+	formatter Formatter
+	mu        sync.Mutex
+}
+
 type Logger struct {
-	out              io.Writer // destination for output
-	infoPrefix       string
-	logPrefix        string
-	warnPrefix       string
-	errorPrefix      string
-	resetColorSuffix string
+	core   *core
+	fields Fields
 }
 
-// Returns a new console logger
+// Modes for coloring the output of the logger
+const (
+	ColorAuto   string = "auto"
+	ColorAlways string = "always"
+	ColorNever  string = "never"
+)
+
+// Returns a new console logger writing text, the output is colored when out is
+// a terminal.
 func New(out io.Writer) *Logger {
-	return &Logger{
-		out:              out,
-		infoPrefix:       fmt.Sprintf("%s[*] ", Blue),
-		logPrefix:        fmt.Sprint("[+] "),
-		warnPrefix:       fmt.Sprintf("%s[!] ", Yellow),
-		errorPrefix:      fmt.Sprintf("%s[-] ", Red),
-		resetColorSuffix: fmt.Sprintf("%s\n", Reset),
+	l := &Logger{
+		core: &core{
+			out:       out,
+			level:     InfoLevel,
+			formatter: &TextFormatter{},
+		},
+	}
+	l.SetColor(ShouldColor(out))
+	return l
+}
+
+// Reports if the output written to out should be colored, which is when out is
+// a terminal unless the NO_COLOR environment variable is set or TERM is dumb.
+func ShouldColor(out io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := out.(*os.File)
+	return ok && IsTerminal(f)
+}
+
+// Checks if the file is a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Returns a logger adding the fields to the ones of l for each message, it shares
+// the output, level and formatter of l.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Logger{core: l.core, fields: merged}
+}
+
+// Returns a logger adding the field to the ones of l for each message
+func (l *Logger) WithField(key string, value interface{}) *Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+// Enable or disable the colors of the text output
+func (l *Logger) SetColor(enabled bool) {
+	l.core.color = enabled
+	if f, ok := l.core.formatter.(*TextFormatter); ok {
+		f.Color = enabled
+	}
+}
+
+// Set the coloring of the output as per the mode, one of auto, always or never
+func (l *Logger) SetColorMode(mode string) error {
+	switch mode {
+	case ColorAuto:
+		l.SetColor(ShouldColor(l.core.out))
+	case ColorAlways:
+		l.SetColor(true)
+	case ColorNever:
+		l.SetColor(false)
+	default:
+		return fmt.Errorf("Unknown color mode %q, it should be one of auto, always or never", mode)
 	}
+	return nil
+}
+
+// Set the formatter rendering the messages
+func (l *Logger) SetFormatter(formatter Formatter) {
+	if f, ok := formatter.(*TextFormatter); ok {
+		f.Color = l.core.color
+	}
+	l.core.formatter = formatter
+}
+
+// Reports if the messages are rendered as human readable text, in which case
+// progress can be displayed interactively along with them.
+func (l *Logger) IsText() bool {
+	_, ok := l.core.formatter.(*TextFormatter)
+	return ok
+}
+
+// Set the minimum level of the messages written by the logger
+func (l *Logger) SetLevel(level Level) {
+	l.core.level = level
 }
 
-// Print output to logger output writer
-func (l *Logger) Output(s string) error {
-	writer := ansicolor.NewAnsiColorWriter(l.out)
-	_, err := fmt.Fprintf(writer, s+l.resetColorSuffix)
+// Returns the minimum level of the messages written by the logger
+func (l *Logger) GetLevel() Level {
+	return l.core.level
+}
+
+// Reports if messages of the level are written by the logger
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.core.level
+}
+
+// Format the message with the fields of the logger and write it to the output
+func (l *Logger) Output(level Level, tag string, s string) error {
+	entry := &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: s,
+		Fields:  l.fields,
+		tag:     tag,
+	}
+	b, err := l.core.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	_, err = ansicolor.NewAnsiColorWriter(l.core.out).Write(b)
 	return err
 }
 
-// Arguments are handled in the manner of fmt.Printf.
+// Print the message with the tag if the level is enabled
+func (l *Logger) output(level Level, tag string, s string) {
+	if l.Enabled(level) {
+		l.Output(level, tag, s)
+	}
+}
+
+// Arguments are handled in the manner of fmt.Printf, logged at info level.
 func (l *Logger) Printf(format string, v ...interface{}) {
-	l.Output(fmt.Sprintf(format, v...))
+	l.output(InfoLevel, "", fmt.Sprintf(format, v...))
+}
+
+// Logs debug
+func (l *Logger) Debug(v ...interface{}) {
+	l.output(DebugLevel, "[~]", fmt.Sprint(v...))
+}
+
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.output(DebugLevel, "[~]", fmt.Sprintf(format, v...))
 }
 
-// Logs log
+// Logs log, at info level
 func (l *Logger) Log(v ...interface{}) {
-	l.Output(l.logPrefix + fmt.Sprint(v...))
+	l.output(InfoLevel, "[+]", fmt.Sprint(v...))
 }
 
 func (l *Logger) Logf(format string, v ...interface{}) {
-	l.Output(l.logPrefix + fmt.Sprintf(format, v...))
+	l.output(InfoLevel, "[+]", fmt.Sprintf(format, v...))
 }
 
 // Logs info
 func (l *Logger) Info(v ...interface{}) {
-	l.Output(l.infoPrefix + fmt.Sprint(v...))
+	l.output(InfoLevel, "[*]", fmt.Sprint(v...))
 }
 
 func (l *Logger) Infof(format string, v ...interface{}) {
-	l.Output(l.infoPrefix + fmt.Sprintf(format, v...))
+	l.output(InfoLevel, "[*]", fmt.Sprintf(format, v...))
 }
 
 // Logs warning
 func (l *Logger) Warn(v ...interface{}) {
-	l.Output(l.warnPrefix + fmt.Sprint(v...))
+	l.output(WarnLevel, "[!]", fmt.Sprint(v...))
 }
 
 func (l *Logger) Warnf(format string, v ...interface{}) {
-	l.Output(l.warnPrefix + fmt.Sprintf(format, v...))
+	l.output(WarnLevel, "[!]", fmt.Sprintf(format, v...))
 }
 
 // Logs error
 func (l *Logger) Error(v ...interface{}) {
-	l.Output(l.errorPrefix + fmt.Sprint(v...))
+	l.output(ErrorLevel, "[-]", fmt.Sprint(v...))
 }
 
 func (l *Logger) Errorf(format string, v ...interface{}) {
-	l.Output(l.errorPrefix + fmt.Sprintf(format, v...))
+	l.output(ErrorLevel, "[-]", fmt.Sprintf(format, v...))
 }
 
-// Fatal is equivalent to l.Print() followed by a call to os.Exit(1).
+// Fatal is equivalent to l.Error() followed by a call to os.Exit(1), the message
+// is written whatever the level of the logger.
 func (l *Logger) Fatal(v ...interface{}) {
-	l.Output(l.errorPrefix + fmt.Sprint(v...))
+	l.Output(ErrorLevel, "[-]", fmt.Sprint(v...))
 	os.Exit(1)
 }
 
 func (l *Logger) Fatalf(format string, v ...interface{}) {
-	l.Output(fmt.Sprintf(format, v...))
+	l.Output(ErrorLevel, "[-]", fmt.Sprintf(format, v...))
 	os.Exit(1)
 }
 
 func (l *Logger) Panic(v ...interface{}) {
 	s := fmt.Sprint(v...)
-	l.Output(s)
+	l.Output(ErrorLevel, "[-]", s)
 	panic(s)
 }
 
 func (l *Logger) Panicf(format string, v ...interface{}) {
 	s := fmt.Sprintf(format, v...)
-	l.Output(s)
+	l.Output(ErrorLevel, "[-]", s)
 	panic(s)
 }
 
-var Log *Logger = New(os.Stdout)
+// Logger shared by all the packages, it writes diagnostics to stderr so that
+// the output of the commands on stdout can be consumed by other programs.
+var Log *Logger = New(os.Stderr)
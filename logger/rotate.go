@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// A lock file older than LOCK_STALE_AFTER is left by a process which crashed
+// and is removed, waiting for the lock fails after LOCK_TIMEOUT.
+const (
+	LOCK_STALE_AFTER time.Duration = 30 * time.Second
+	LOCK_TIMEOUT     time.Duration = 10 * time.Second
+)
+
+// Writer appending to a file which is rotated once it grows over a maximum size,
+// the rotated files are kept as path.1 (the most recent) up to path.<backups>.
+// Each write holds the lock file path.lock, so that processes sharing the file
+// never rotate it twice or append to a file which was just rotated.
+type RotatingWriter struct {
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+	mu      sync.Mutex
+}
+
+// Open the file at path for appending, rotating it when it would grow over
+// maxSize bytes and keeping at most backups rotated files.
+func NewRotatingWriter(path string, maxSize int64, backups int) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		path:    path,
+		maxSize: maxSize,
+		backups: backups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Returns the path of the nth rotated file
+func (w *RotatingWriter) backup(n int) string {
+	return fmt.Sprintf("%s.%d", w.path, n)
+}
+
+// Shift the rotated files, dropping the oldest one, and start a new file
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	for n := w.backups - 1; n > 0; n-- {
+		if err := os.Rename(w.backup(n), w.backup(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if w.backups > 0 {
+		if err := os.Rename(w.path, w.backup(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(w.path); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Create the lock file at path, waiting while another process holds it, and
+// returns the function releasing it.
+func lockFile(path string) (func() error, error) {
+	deadline := time.Now().Add(LOCK_TIMEOUT)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > LOCK_STALE_AFTER {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timed out waiting for the lock %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Reopen the file when it was rotated by another process and update its size
+// with what the other processes appended.
+func (w *RotatingWriter) refresh() error {
+	current, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(w.path)
+	if err == nil && os.SameFile(info, current) {
+		w.size = current.Size()
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	w.file.Close()
+	return w.open()
+}
+
+func (w *RotatingWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	unlock, err := lockFile(w.path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if e := unlock(); err == nil {
+			err = e
+		}
+	}()
+
+	if err := w.refresh(); err != nil {
+		return 0, err
+	}
+	// A file which is still empty is never rotated, even for a larger write
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
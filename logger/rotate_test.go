@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestRotatingWriterConcurrentWriters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gvm-rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gvm.log")
+
+	// Writers with their own file, like processes sharing the log
+	const writers, lines, backups = 4, 50, 100
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		w, err := NewRotatingWriter(path, 256, backups)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int, w *RotatingWriter) {
+			defer wg.Done()
+			defer w.Close()
+			for j := 0; j < lines; j++ {
+				if _, err := fmt.Fprintf(w, "writer %d line %02d\n", i, j); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(i, w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// Every line is found once, whole, in a file which did not grow over the maximum size
+	seen := make(map[string]bool)
+	files := []string{path}
+	for n := 1; n <= backups; n++ {
+		files = append(files, fmt.Sprintf("%s.%d", path, n))
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, err := f.Stat(); err != nil || info.Size() > 256 {
+			t.Errorf("%s grew over the maximum size", file)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if seen[scanner.Text()] {
+				t.Errorf("line %q was written twice", scanner.Text())
+			}
+			seen[scanner.Text()] = true
+		}
+		f.Close()
+	}
+	if len(seen) != writers*lines {
+		t.Errorf("found %d lines, expected %d", len(seen), writers*lines)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("the lock file was not removed")
+	}
+}
@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Environment variable controlling the microarchitecture level of a GOARCH with
+// the levels it accepts. A level can be followed by comma separated features,
+// when no levels are listed the value is only a list of features.
+type microArch struct {
+	envVar   string
+	levels   []string
+	features []string
+}
+
+var (
+	mipsFloat   = []string{"hardfloat", "softfloat"}
+	ppc64Levels = []string{"power8", "power9", "power10"}
+)
+
+// Microarchitecture levels targeted by the compiler for each GOARCH
+var microArchs = map[string]microArch{
+	"386":   {envVar: "GO386", levels: []string{"sse2", "softfloat"}},
+	"amd64": {envVar: "GOAMD64", levels: []string{"v1", "v2", "v3", "v4"}},
+	"arm":   {envVar: "GOARM", levels: []string{"5", "6", "7"}, features: []string{"softfloat", "hardfloat"}},
+	"arm64": {envVar: "GOARM64", levels: []string{
+		"v8.0", "v8.1", "v8.2", "v8.3", "v8.4", "v8.5", "v8.6", "v8.7", "v8.8", "v8.9",
+		"v9.0", "v9.1", "v9.2", "v9.3", "v9.4", "v9.5",
+	}, features: []string{"lse", "crypto"}},
+	"mips":     {envVar: "GOMIPS", levels: mipsFloat},
+	"mipsle":   {envVar: "GOMIPS", levels: mipsFloat},
+	"mips64":   {envVar: "GOMIPS64", levels: mipsFloat},
+	"mips64le": {envVar: "GOMIPS64", levels: mipsFloat},
+	"ppc64":    {envVar: "GOPPC64", levels: ppc64Levels},
+	"ppc64le":  {envVar: "GOPPC64", levels: ppc64Levels},
+	"riscv64":  {envVar: "GORISCV64", levels: []string{"rva20u64", "rva22u64", "rva23u64"}},
+	"wasm":     {envVar: "GOWASM", features: []string{"satconv", "signext"}},
+}
+
+// Check that the value is a level of the architecture followed by its features
+func (m microArch) check(value string) error {
+	parts := strings.Split(value, ",")
+	if len(m.levels) > 0 {
+		if !contains(m.levels, parts[0]) {
+			return fmt.Errorf("Invalid microarchitecture level %s for %s, should be one of %s",
+				parts[0], m.envVar, strings.Join(m.levels, ", "))
+		}
+		parts = parts[1:]
+	}
+	for _, feature := range parts {
+		if !contains(m.features, feature) {
+			return fmt.Errorf("Invalid feature %s for %s", feature, m.envVar)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Options controlling the compilation of go from source, these are passed
+// explicitly to make.bash and recorded in the metadata of the installation.
+type BuildOptions struct {
+	CgoEnabled string `json:"cgo_enabled,omitempty" toml:"cgo_enabled,omitempty"`
+	Experiment string `json:"goexperiment,omitempty" toml:"goexperiment,omitempty"`
+	GcFlags    string `json:"gcflags,omitempty" toml:"gcflags,omitempty"`
+	LdFlags    string `json:"ldflags,omitempty" toml:"ldflags,omitempty"`
+	MicroArch  string `json:"microarch,omitempty" toml:"microarch,omitempty"`
+	NoClean    bool   `json:"no_clean,omitempty" toml:"no_clean,omitempty"`
+}
+
+// Check that the options can be used for the compilation on the host architecture
+func (o BuildOptions) Validate() error {
+	return o.validate(runtime.GOARCH)
+}
+
+func (o BuildOptions) validate(goarch string) error {
+	if o.CgoEnabled != "" && o.CgoEnabled != "0" && o.CgoEnabled != "1" {
+		return fmt.Errorf("Invalid value %s for CGO_ENABLED, should be 0 or 1", o.CgoEnabled)
+	}
+	if o.MicroArch != "" {
+		arch, ok := microArchs[goarch]
+		if !ok {
+			return fmt.Errorf("No microarchitecture levels are supported for %s", goarch)
+		}
+		return arch.check(o.MicroArch)
+	}
+	return nil
+}
+
+// Returns the environment variables for make.bash corresponding to the options
+func (o BuildOptions) Env() []string {
+	return o.env(runtime.GOARCH)
+}
+
+func (o BuildOptions) env(goarch string) []string {
+	env := make([]string, 0)
+	if o.CgoEnabled != "" {
+		env = append(env, "CGO_ENABLED="+o.CgoEnabled)
+	}
+	if o.Experiment != "" {
+		env = append(env, "GOEXPERIMENT="+o.Experiment)
+	}
+	if o.GcFlags != "" {
+		env = append(env, "GO_GCFLAGS="+o.GcFlags)
+	}
+	if o.LdFlags != "" {
+		env = append(env, "GO_LDFLAGS="+o.LdFlags)
+	}
+	if o.MicroArch != "" {
+		env = append(env, microArchs[goarch].envVar+"="+o.MicroArch)
+	}
+	return env
+}
+
+// Returns the arguments for make.bash corresponding to the options
+func (o BuildOptions) Args() []string {
+	args := make([]string, 0)
+	if o.NoClean {
+		args = append(args, "--no-clean")
+	}
+	return args
+}
+
+// Reports if no option was set
+func (o BuildOptions) IsZero() bool {
+	return o == BuildOptions{}
+}
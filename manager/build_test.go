@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildOptionsValidate(t *testing.T) {
+	tests := []struct {
+		goarch string
+		opts   BuildOptions
+		valid  bool
+	}{
+		{"amd64", BuildOptions{}, true},
+		{"amd64", BuildOptions{CgoEnabled: "0"}, true},
+		{"amd64", BuildOptions{CgoEnabled: "1"}, true},
+		{"amd64", BuildOptions{CgoEnabled: "2"}, false},
+		{"amd64", BuildOptions{MicroArch: "v1"}, true},
+		{"amd64", BuildOptions{MicroArch: "v4"}, true},
+		{"amd64", BuildOptions{MicroArch: "v5"}, false},
+		{"amd64", BuildOptions{MicroArch: "7"}, false},
+		{"386", BuildOptions{MicroArch: "sse2"}, true},
+		{"386", BuildOptions{MicroArch: "softfloat"}, true},
+		{"386", BuildOptions{MicroArch: "387"}, false},
+		{"arm", BuildOptions{MicroArch: "5"}, true},
+		{"arm", BuildOptions{MicroArch: "7,softfloat"}, true},
+		{"arm", BuildOptions{MicroArch: "8"}, false},
+		{"arm", BuildOptions{MicroArch: "7,lse"}, false},
+		{"arm64", BuildOptions{MicroArch: "v8.0"}, true},
+		{"arm64", BuildOptions{MicroArch: "v9.2,lse,crypto"}, true},
+		{"arm64", BuildOptions{MicroArch: "v3"}, false},
+		{"mipsle", BuildOptions{MicroArch: "softfloat"}, true},
+		{"mips64", BuildOptions{MicroArch: "sse2"}, false},
+		{"ppc64le", BuildOptions{MicroArch: "power9"}, true},
+		{"ppc64", BuildOptions{MicroArch: "power7"}, false},
+		{"riscv64", BuildOptions{MicroArch: "rva22u64"}, true},
+		{"wasm", BuildOptions{MicroArch: "satconv,signext"}, true},
+		{"wasm", BuildOptions{MicroArch: "simd"}, false},
+		{"s390x", BuildOptions{MicroArch: "z13"}, false},
+		{"s390x", BuildOptions{CgoEnabled: "1"}, true},
+	}
+
+	for _, test := range tests {
+		err := test.opts.validate(test.goarch)
+		if test.valid && err != nil {
+			t.Errorf("%+v on %s : unexpected error %v", test.opts, test.goarch, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%+v on %s : expected an error", test.opts, test.goarch)
+		}
+	}
+}
+
+func TestBuildOptionsEnv(t *testing.T) {
+	tests := []struct {
+		goarch   string
+		opts     BuildOptions
+		expected []string
+	}{
+		{"amd64", BuildOptions{}, []string{}},
+		{"amd64", BuildOptions{NoClean: true}, []string{}},
+		{"amd64", BuildOptions{
+			CgoEnabled: "0",
+			Experiment: "loopvar",
+			GcFlags:    "all=-N -l",
+			LdFlags:    "-s -w",
+			MicroArch:  "v3",
+		}, []string{
+			"CGO_ENABLED=0",
+			"GOEXPERIMENT=loopvar",
+			"GO_GCFLAGS=all=-N -l",
+			"GO_LDFLAGS=-s -w",
+			"GOAMD64=v3",
+		}},
+		{"arm", BuildOptions{MicroArch: "6"}, []string{"GOARM=6"}},
+		{"mips64le", BuildOptions{MicroArch: "softfloat"}, []string{"GOMIPS64=softfloat"}},
+	}
+
+	for _, test := range tests {
+		if env := test.opts.env(test.goarch); !reflect.DeepEqual(env, test.expected) {
+			t.Errorf("%+v on %s : env %q, expected %q", test.opts, test.goarch, env, test.expected)
+		}
+	}
+}
+
+func TestBuildOptionsArgs(t *testing.T) {
+	if args := (BuildOptions{CgoEnabled: "0"}).Args(); len(args) != 0 {
+		t.Errorf("unexpected arguments %q", args)
+	}
+	if args := (BuildOptions{NoClean: true}).Args(); !reflect.DeepEqual(args, []string{"--no-clean"}) {
+		t.Errorf("unexpected arguments %q", args)
+	}
+}
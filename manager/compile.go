@@ -1,29 +1,38 @@
 package manager
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
 
+	"github.com/fristonio/gvm/logger"
 	"github.com/fristonio/gvm/utils"
 )
 
-func CompileGoRelease(releaseName string) error {
-	err := CreateCompilationEnv(releaseName)
+// Compile the extracted go source for the release running make.bash with
+// the environment created for it and the provided build options. The output
+// of the build is logged to a new build log for the release, when quiet it is
+// not shown on the terminal. make.bash and the processes it started are
+// killed once ctx is done.
+func CompileGoRelease(ctx context.Context, paths utils.Paths, log *logger.Logger, releaseName string, opts BuildOptions, quiet bool) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	env, err := CreateCompilationEnv(paths, releaseName, opts)
 	if err != nil {
 		return err
 	}
-	goSrcDir := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_GOS_DIRNAME, releaseName, "src")
-	os.Chdir(goSrcDir)
+	goSrcDir := paths.Join(utils.GVM_GOS_DIRNAME, releaseName, "src")
 
-	cmd := exec.Command("./make.bash")
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
+	cmd := exec.Command("./make.bash", opts.Args()...)
+	cmd.Dir = goSrcDir
+	cmd.Env = env
+	_, err = runLogged(ctx, cmd, paths, log, releaseName, BUILD_LOG, quiet)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
-		return fmt.Errorf("Error while running compilation : %v\n", err)
+		return fmt.Errorf("Error while running compilation : %v", err)
 	}
 	return nil
 }
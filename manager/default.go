@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+// Mark the go version as the default one, the default environment file in the
+// environment directory is linked to the environment file of the version.
+// A version whose self test failed is refused unless force is set.
+func SetDefault(paths utils.Paths, goVersion string, force bool) error {
+	environmentDir := paths.Join(utils.GVM_ENV_DIRNAME)
+	if !utils.CheckIfAlreadyExist(filepath.Join(environmentDir, goVersion)) {
+		return fmt.Errorf("No environment found for %s, is it installed?", goVersion)
+	}
+
+	meta, err := ReadMetadata(paths, goVersion)
+	if err != nil {
+		return err
+	}
+	if meta.SelfTest != nil && !meta.SelfTest.Passed && !force {
+		return fmt.Errorf("Self test of %s failed on %s, refusing to make it the default",
+			goVersion, meta.SelfTest.Time.Format("2006-01-02 15:04"))
+	}
+
+	defaultEnv := filepath.Join(environmentDir, utils.GVM_DEFAULT_ENV)
+	if err = os.Remove(defaultEnv); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(goVersion, defaultEnv)
+}
+
+// Returns the name of the default go version, empty if there is none
+func GetDefault(paths utils.Paths) string {
+	target, err := os.Readlink(paths.Join(utils.GVM_ENV_DIRNAME, utils.GVM_DEFAULT_ENV))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// Remove the default environment file, leaving no version as the default one
+func UnsetDefault(paths utils.Paths) error {
+	err := os.Remove(paths.Join(utils.GVM_ENV_DIRNAME, utils.GVM_DEFAULT_ENV))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
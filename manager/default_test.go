@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+func TestSetDefault(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	paths := utils.Paths{Root: root, Pkgset: utils.GVM_PKGSET_NAME}
+
+	environmentDir := filepath.Join(root, utils.GVM_ENV_DIRNAME)
+	if err = os.MkdirAll(environmentDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"go1.9", "go1.10"} {
+		if err = ioutil.WriteFile(filepath.Join(environmentDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed := &Metadata{Name: "go1.10", SelfTest: &SelfTestResult{Passed: false, Time: time.Now()}}
+	if err = WriteMetadata(paths, failed); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = SetDefault(paths, "go1.11", false); err == nil {
+		t.Error("a version which is not installed was made the default")
+	}
+	if err = SetDefault(paths, "go1.9", false); err != nil {
+		t.Fatal(err)
+	}
+	if GetDefault(paths) != "go1.9" {
+		t.Errorf("default is %q, expected go1.9", GetDefault(paths))
+	}
+
+	if err = SetDefault(paths, "go1.10", false); err == nil {
+		t.Error("a version whose self test failed was made the default")
+	}
+	if GetDefault(paths) != "go1.9" {
+		t.Errorf("default changed to %q after a refusal", GetDefault(paths))
+	}
+	if err = SetDefault(paths, "go1.10", true); err != nil {
+		t.Fatal(err)
+	}
+	if GetDefault(paths) != "go1.10" {
+		t.Errorf("default is %q, expected go1.10 when forced", GetDefault(paths))
+	}
+
+	if err = UnsetDefault(paths); err != nil {
+		t.Fatal(err)
+	}
+	if GetDefault(paths) != "" {
+		t.Errorf("default is %q after it was unset", GetDefault(paths))
+	}
+}
@@ -1,10 +1,13 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/fristonio/gvm/logger"
 	"github.com/fristonio/gvm/utils"
 )
 
@@ -28,14 +31,14 @@ import (
 
 10 directories, 0 files
 */
-func CreateGlobalPackageSets(goVersion string) error {
-	gvmPkgSet := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_PKGSET_DIRNAME, goVersion)
+func CreateGlobalPackageSets(paths utils.Paths, goVersion string) error {
+	gvmPkgSet := paths.Join(utils.GVM_PKGSET_DIRNAME, goVersion)
 	err := utils.CreateDirStrucutre(gvmPkgSet)
 	if err != nil {
 		return fmt.Errorf("Error while creating GVM packageset")
 	}
 
-	gvmOverlayRoot := filepath.Join(gvmPkgSet, utils.GVM_PKGSET_NAME, utils.GVM_OVERLAY_DIRNAME)
+	gvmOverlayRoot := filepath.Join(gvmPkgSet, paths.Pkgset, utils.GVM_OVERLAY_DIRNAME)
 
 	gvmOverlayPkgConfig := filepath.Join(gvmOverlayRoot, "lib", "pkgconfig")
 	err = utils.CreateDirStrucutre(gvmOverlayPkgConfig)
@@ -55,17 +58,17 @@ func CreateGlobalPackageSets(goVersion string) error {
 // Each go version in associated  with an environment shell script
 // Which creates the required environment for that version of go
 // version specifies the version of the golang we are creating the env for
-func CreateEnvironmentFile(goVersion string) error {
-	CreateGlobalPackageSets(goVersion)
-	if utils.GOS_REGEXP.FindString(goVersion) == "" {
+func CreateEnvironmentFile(paths utils.Paths, log *logger.Logger, goVersion string) error {
+	CreateGlobalPackageSets(paths, goVersion)
+	if !utils.IsValidGoName(goVersion) {
 		errStr := fmt.Sprintf("Not a valid go name %s to create environment", goVersion)
-		utils.Log.Warn(errStr)
-		return fmt.Errorf(errStr)
+		log.Warn(errStr)
+		return errors.New(errStr)
 	}
-	var environmentDir string = filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_ENV_DIRNAME)
+	var environmentDir string = paths.Join(utils.GVM_ENV_DIRNAME)
 	err := utils.CreateDirStrucutre(environmentDir)
 	if err != nil {
-		utils.Log.Warnf("An error occured while creating enviroment directory : %s", environmentDir)
+		log.Warnf("An error occured while creating enviroment directory : %s", environmentDir)
 		return err
 	}
 	var environmentFile string = filepath.Join(environmentDir, goVersion)
@@ -79,76 +82,121 @@ func CreateEnvironmentFile(goVersion string) error {
 	err = nil
 	file, err := os.OpenFile(environmentFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0775)
 	if err != nil {
-		errStr := fmt.Sprintf("An error occured while opening file : %s", environmentFile)
-		return fmt.Errorf(errStr)
+		return fmt.Errorf("An error occured while opening file : %s", environmentFile)
 	}
 	defer file.Close()
 
-	// Get the variables ready for environment file
-	gvmGosRoot := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_GOS_DIRNAME, goVersion)
-	gvmGoPath := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_PKGSET_DIRNAME, goVersion, utils.GVM_PKGSET_NAME)
+	variables := environmentVariables(paths, goVersion, func(name string) string {
+		return "$" + name
+	})
+	values := make([]interface{}, len(variables))
+	for i, variable := range variables {
+		values[i] = variable[1]
+	}
+	goEnv := fmt.Sprintf(utils.ENV_FILE, values...)
+
+	_, e := file.WriteString(goEnv)
+	if e != nil {
+		return fmt.Errorf("An error occured while writing the environment configuration file")
+	}
+	return nil
+}
+
+// Returns the variables of the environment of the go version as NAME=value, the
+// search paths of the version are prepended to the ones of the current process
+// as done by sourcing its environment file.
+func Environment(paths utils.Paths, goVersion string) []string {
+	env := make([]string, 0)
+	for _, variable := range environmentVariables(paths, goVersion, os.Getenv) {
+		env = append(env, variable[0]+"="+variable[1])
+	}
+	return env
+}
+
+// Returns the name and value of the variables of the environment of the go version
+// in the order of the environment file, previous returns the value a search path
+// is prepended to.
+func environmentVariables(paths utils.Paths, goVersion string, previous func(string) string) [][2]string {
+	gvmGosRoot := paths.Join(utils.GVM_GOS_DIRNAME, goVersion)
+	gvmGoPath := paths.Join(utils.GVM_PKGSET_DIRNAME, goVersion, paths.Pkgset)
 	gvmGoOverlayPath := filepath.Join(gvmGoPath, utils.GVM_OVERLAY_DIRNAME)
 
 	gvmGosRootBin := filepath.Join(gvmGosRoot, "bin")
 	gvmGoBinPath := filepath.Join(gvmGoPath, "bin")
 	gvmGoOverlayBinPath := filepath.Join(gvmGoOverlayPath, "bin")
-	gvmRootBinPath := filepath.Join(utils.GVM_ROOT_DIR, "bin")
-	newENVPath := gvmGosRootBin + ":" + gvmGoBinPath + ":" + gvmGoOverlayBinPath + ":" + gvmRootBinPath + `:$PATH`
+	gvmRootBinPath := paths.Join("bin")
+	newENVPath := gvmGosRootBin + ":" + gvmGoBinPath + ":" + gvmGoOverlayBinPath + ":" + gvmRootBinPath + ":" + previous("PATH")
 
 	gvmOverlayLibPath := filepath.Join(gvmGoOverlayPath, "lib")
-	newLdLibPath := gvmOverlayLibPath + ":$LD_LIBRARY_PATH"
-	newDyldLibPath := gvmOverlayLibPath + ":$DYLD_LIBRARY_PATH"
+	newLdLibPath := gvmOverlayLibPath + ":" + previous("LD_LIBRARY_PATH")
+	newDyldLibPath := gvmOverlayLibPath + ":" + previous("DYLD_LIBRARY_PATH")
 
 	gvmOverlayPkgConfig := filepath.Join(gvmOverlayLibPath, "pkgconfig")
-	newPkgConfigPath := gvmOverlayPkgConfig + ":$PKG_CONFIG_PATH"
-
-	goEnv := fmt.Sprintf(utils.ENV_FILE,
-		utils.GVM_ROOT_DIR,
-		goVersion,
-		utils.GVM_PKGSET_NAME,
-		gvmGosRoot,
-		gvmGoPath,
-		gvmGoOverlayPath,
-		newENVPath,
-		newLdLibPath,
-		newDyldLibPath,
-		newPkgConfigPath,
-	)
-
-	_, e := file.WriteString(goEnv)
-	if e != nil {
-		return fmt.Errorf("An error occured while writing the environment configuration file")
+	newPkgConfigPath := gvmOverlayPkgConfig + ":" + previous("PKG_CONFIG_PATH")
+
+	return [][2]string{
+		{"GVM_ROOT", paths.Root},
+		{"GVM_GO_VERSION", goVersion},
+		{"GVM_PACKAGESET_NAME", paths.Pkgset},
+		{"GOROOT", gvmGosRoot},
+		{"GOPATH", gvmGoPath},
+		{"GVM_OVERLAY_PREFIX", gvmGoOverlayPath},
+		{"PATH", newENVPath},
+		{"LD_LIBRARY_PATH", newLdLibPath},
+		{"DYLD_LIBRARY_PATH", newDyldLibPath},
+		{"PKG_CONFIG_PATH", newPkgConfigPath},
 	}
-	return nil
 }
 
 // Create environment for compilation of go from source
-// Unsets previously set env variable and set to new ones.
+// The environment of gvm is used as the base, from which the variables associated
+// with the parent installation and the ones controlled by build options are removed
+// before setting them for the new installation.
 // Take a look at manager/new_installation.md to get an insight for the procedure
-func CreateCompilationEnv(goVersion string) error {
+func CreateCompilationEnv(paths utils.Paths, goVersion string, opts BuildOptions) ([]string, error) {
 	var pathEnvVar string = os.Getenv("PATH")
-	var goVerDir string = filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_GOS_DIRNAME, goVersion)
+	var goVerDir string = paths.Join(utils.GVM_GOS_DIRNAME, goVersion)
 	var gobinEnvPath string = filepath.Join(goVerDir, "bin")
 
 	err := utils.CheckIfDirExist(goVerDir)
 	if err != nil {
-		errStr := fmt.Sprintf("No sources with goVersion %s found.", goVersion)
-		return fmt.Errorf(errStr)
+		return nil, fmt.Errorf("No sources with goVersion %s found.", goVersion)
 	}
 
 	var baseGoRoot string = os.Getenv("GOROOT")
 	if baseGoRoot == "" {
-		return fmt.Errorf("No GOROOT environment variable")
-	}
-	os.Setenv("GOROOT_BOOTSTRAP", baseGoRoot)
-	os.Unsetenv("GOARCH")
-	os.Unsetenv("GOOS")
-	os.Unsetenv("GOPATH")
-	os.Unsetenv("GOBIN")
-	os.Unsetenv("GOROOT")
-
-	os.Setenv("GOBIN", gobinEnvPath)
-	os.Setenv("PATH", gobinEnvPath+pathEnvVar)
-	os.Setenv("GOROOT", goVerDir)
-	return nil
+		return nil, fmt.Errorf("No GOROOT environment variable")
+	}
+
+	unset := map[string]bool{
+		"GOARCH":           true,
+		"GOOS":             true,
+		"GOPATH":           true,
+		"GOBIN":            true,
+		"GOROOT":           true,
+		"GOROOT_BOOTSTRAP": true,
+		"PATH":             true,
+		"CGO_ENABLED":      true,
+		"GOEXPERIMENT":     true,
+		"GO_GCFLAGS":       true,
+		"GO_LDFLAGS":       true,
+	}
+	for _, arch := range microArchs {
+		unset[arch.envVar] = true
+	}
+
+	env := make([]string, 0)
+	for _, kv := range os.Environ() {
+		if !unset[strings.SplitN(kv, "=", 2)[0]] {
+			env = append(env, kv)
+		}
+	}
+
+	env = append(env,
+		"GOROOT_BOOTSTRAP="+baseGoRoot,
+		"GOBIN="+gobinEnvPath,
+		"PATH="+gobinEnvPath+string(os.PathListSeparator)+pathEnvVar,
+		"GOROOT="+goVerDir,
+	)
+	return append(env, opts.Env()...), nil
 }
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Name of the operation log in the logs directory, it is rotated once it grows
+// over HISTORY_MAX_SIZE keeping HISTORY_BACKUPS rotated files.
+const (
+	HISTORY_FILE     string = "gvm.log"
+	HISTORY_MAX_SIZE int64  = 1 << 20
+	HISTORY_BACKUPS  int    = 3
+)
+
+// Outcomes of an operation
+const (
+	OPERATION_SUCCEEDED string = "success"
+	OPERATION_FAILED    string = "failure"
+)
+
+// A command run by gvm which changed the installations, recorded in the operation log
+type Operation struct {
+	Start    time.Time `json:"start"`
+	Command  string    `json:"command"`
+	Args     []string  `json:"args"`
+	Duration string    `json:"duration"`
+	Outcome  string    `json:"outcome"`
+	ExitCode int       `json:"exit_code"`
+}
+
+func historyFile(paths utils.Paths) string {
+	return paths.Join(utils.GVM_LOGS_DIRNAME, HISTORY_FILE)
+}
+
+// Append the operation to the operation log as a json line, the error is returned
+// when the line could not be written.
+func RecordOperation(paths utils.Paths, op Operation) error {
+	if err := utils.CreateDirStrucutre(filepath.Dir(historyFile(paths))); err != nil {
+		return err
+	}
+	line, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	w, err := logger.NewRotatingWriter(historyFile(paths), HISTORY_MAX_SIZE, HISTORY_BACKUPS)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Returns the operations recorded in the operation log, oldest first
+func ReadHistory(paths utils.Paths) ([]Operation, error) {
+	files := make([]string, 0, HISTORY_BACKUPS+1)
+	for n := HISTORY_BACKUPS; n > 0; n-- {
+		files = append(files, fmt.Sprintf("%s.%d", historyFile(paths), n))
+	}
+	files = append(files, historyFile(paths))
+
+	operations := make([]Operation, 0)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return operations, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var op Operation
+			// Skip lines which are not operations, like a line truncated by a crash
+			if err := json.Unmarshal(scanner.Bytes(), &op); err != nil || op.Command == "" {
+				continue
+			}
+			operations = append(operations, op)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return operations, err
+		}
+	}
+	return operations, nil
+}
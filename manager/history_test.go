@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+func TestRecordOperation(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	paths := utils.Paths{Root: root, Pkgset: "global"}
+
+	recorded := []Operation{
+		{Command: "install", Args: []string{"install", "go1.9"}, Outcome: OPERATION_SUCCEEDED},
+		{Command: "use", Args: []string{"use", "go1.10"}, Outcome: OPERATION_FAILED, ExitCode: 3},
+	}
+	for i := range recorded {
+		recorded[i].Start = time.Unix(1500000000+int64(i), 0).UTC()
+		if err := RecordOperation(paths, recorded[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	operations, err := ReadHistory(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != len(recorded) {
+		t.Fatalf("read %d operations, expected %d", len(operations), len(recorded))
+	}
+	for i, op := range operations {
+		if op.Command != recorded[i].Command || op.ExitCode != recorded[i].ExitCode ||
+			op.Outcome != recorded[i].Outcome || !op.Start.Equal(recorded[i].Start) {
+			t.Errorf("read %+v, expected %+v", op, recorded[i])
+		}
+	}
+
+	// The operation log can not be written once it is replaced by a directory
+	if err := os.Remove(historyFile(paths)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(historyFile(paths), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordOperation(paths, recorded[0]); err == nil {
+		t.Error("expected the operation not to be recorded")
+	}
+}
@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Kinds of logs kept for a go version, the kind is used as the suffix of the log file
+const (
+	BUILD_LOG    string = ".log"
+	SELFTEST_LOG string = ".selftest.log"
+)
+
+// Number of lines of the log printed when a quiet run fails
+const logTailLines = 30
+
+// Time the output of a command is still copied for once it exited, processes it
+// started outside of its process group may keep its output open forever.
+const outputDelay = 5 * time.Second
+
+// Create a new log file for the go version in the logs directory, the file is
+// named after the current time with microseconds, for example
+// ~/.gvm/logs/go1.9/20180412-102030.123456.log. An existing log is never
+// overwritten, the name is taken again if a run created it in the meantime.
+func NewLogFile(paths utils.Paths, goVersion string, kind string) (*os.File, error) {
+	logsDir := paths.Join(utils.GVM_LOGS_DIRNAME, goVersion)
+	if err := utils.CreateDirStrucutre(logsDir); err != nil {
+		return nil, err
+	}
+
+	for {
+		logFile := filepath.Join(logsDir, time.Now().Format("20060102-150405.000000")+kind)
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return f, err
+		}
+		time.Sleep(time.Microsecond)
+	}
+}
+
+// Returns the path of the latest log of the given kind for the go version
+func LastLogFile(paths utils.Paths, goVersion string, kind string) (string, error) {
+	logsDir := paths.Join(utils.GVM_LOGS_DIRNAME, goVersion)
+	files, err := filepath.Glob(filepath.Join(logsDir, "*"+kind))
+	if err != nil {
+		return "", err
+	}
+
+	logs := make([]string, 0)
+	for _, f := range files {
+		// Build logs suffix also matches the other kinds of logs
+		if kind == BUILD_LOG && strings.HasSuffix(f, SELFTEST_LOG) {
+			continue
+		}
+		logs = append(logs, f)
+	}
+	if len(logs) == 0 {
+		return "", fmt.Errorf("No logs found for %s", goVersion)
+	}
+
+	sort.Strings(logs)
+	return logs[len(logs)-1], nil
+}
+
+// Returns the last n lines of the file
+func TailFile(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make([]string, 0, n)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(lines) == n {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Run the command for the go version with its output teed into a new log file of
+// the kind. When quiet the output is only written to the log file while a spinner
+// is shown, and the tail of the log is printed if the command fails. The command
+// and the processes it started are killed once ctx is done.
+// Returns the path of the log file.
+func runLogged(ctx context.Context, cmd *exec.Cmd, paths utils.Paths, log *logger.Logger, goVersion string, kind string, quiet bool) (string, error) {
+	logFile, err := NewLogFile(paths, goVersion, kind)
+	if err != nil {
+		return "", err
+	}
+	defer logFile.Close()
+
+	// The output of the command would be mixed with the structured log lines
+	text := log.IsText()
+	var output io.Writer = logFile
+	if !quiet && text {
+		output = io.MultiWriter(os.Stderr, logFile)
+	}
+
+	var spinner *utils.Spinner
+	if quiet && text {
+		spinner = utils.NewSpinner(fmt.Sprintf("Running %s for %s", filepath.Base(cmd.Path), goVersion))
+		spinner.Start()
+	}
+	err = runCommand(ctx, cmd, output)
+	if spinner != nil {
+		spinner.Stop()
+	}
+
+	if err != nil && (quiet || !text) {
+		if lines, e := TailFile(logFile.Name(), logTailLines); e == nil {
+			for _, line := range lines {
+				if text {
+					fmt.Fprintln(os.Stderr, line)
+				} else {
+					log.WithField("version", goVersion).Error(line)
+				}
+			}
+		}
+	}
+	if err != nil {
+		log.WithField("log", logFile.Name()).Warnf("Complete output is available in %s", logFile.Name())
+	}
+	return logFile.Name(), err
+}
+
+// Run the command in its own process group with its output written to w. Once ctx
+// is done the whole group is killed, so the compiler or the tests run by the command
+// do not outlive it, and its output is only waited for outputDelay once it exited.
+func runCommand(ctx context.Context, cmd *exec.Cmd, w io.Writer) error {
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	setProcessGroup(cmd)
+	err = cmd.Start()
+	// The processes hold the write end of the pipe, it is closed once they all exited
+	pw.Close()
+	if err != nil {
+		return err
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(w, r)
+		close(copied)
+	}()
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-exited:
+		}
+	}()
+
+	err = cmd.Wait()
+	close(exited)
+	select {
+	case <-copied:
+	case <-time.After(outputDelay):
+	}
+	return err
+}
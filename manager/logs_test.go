@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+func TestNewLogFileDoesNotOverwrite(t *testing.T) {
+	root, err := ioutil.TempDir("", "gvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	paths := utils.Paths{Root: root, Pkgset: "global"}
+
+	names := make(map[string]bool)
+	var last string
+	for i := 0; i < 20; i++ {
+		f, err := NewLogFile(paths, "go1.9", BUILD_LOG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if names[f.Name()] {
+			t.Fatalf("log file %s was created twice", f.Name())
+		}
+		names[f.Name()] = true
+		last = f.Name()
+	}
+
+	found, err := LastLogFile(paths, "go1.9", BUILD_LOG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != last {
+		t.Errorf("last log is %s, expected %s", found, last)
+	}
+}
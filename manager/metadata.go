@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+// Modes of installation of a go version
+const (
+	SOURCE_INSTALL string = "source"
+	BINARY_INSTALL string = "binary"
+)
+
+// Metadata recorded for each installed go version, it is stored as a json
+// file in the metadata directory under gvm root and removed on uninstall.
+type Metadata struct {
+	Name string `json:"name"`
+	Mode string `json:"mode,omitempty"`
+	// Time the installation completed, zero for the ones recorded before it was
+	InstalledAt time.Time `json:"installed_at"`
+	// Sha256 of the archive the installation was extracted from
+	ArchiveSha256 string         `json:"archive_sha256,omitempty"`
+	Patches       []AppliedPatch `json:"patches,omitempty"`
+	Build         BuildOptions   `json:"build"`
+	// GOARCH the installation was built for, the microarchitecture level of the
+	// build options only applies to it.
+	Arch     string          `json:"goarch,omitempty"`
+	SelfTest *SelfTestResult `json:"selftest,omitempty"`
+}
+
+// Reports if any patch was applied to the source tree of the installation
+func (m *Metadata) IsPatched() bool {
+	return len(m.Patches) > 0
+}
+
+func metadataFile(paths utils.Paths, goVersion string) string {
+	return paths.Join(utils.GVM_META_DIRNAME, goVersion+".json")
+}
+
+// Read the metadata for the go version, if no metadata was ever recorded for it
+// an empty metadata with just the name is returned.
+func ReadMetadata(paths utils.Paths, goVersion string) (*Metadata, error) {
+	meta := &Metadata{Name: goVersion}
+	content, err := ioutil.ReadFile(metadataFile(paths, goVersion))
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+
+	if err = json.Unmarshal(content, meta); err != nil {
+		return meta, fmt.Errorf("Invalid metadata for %s : %v", goVersion, err)
+	}
+	return meta, nil
+}
+
+// Write the metadata to the metadata directory, replacing any previous one
+func WriteMetadata(paths utils.Paths, meta *Metadata) error {
+	if err := utils.CreateDirStrucutre(paths.Join(utils.GVM_META_DIRNAME)); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(metadataFile(paths, meta.Name), content, 0644)
+}
+
+// Remove the metadata recorded for the go version if there is any
+func RemoveMetadata(paths utils.Paths, goVersion string) error {
+	err := os.Remove(metadataFile(paths, goVersion))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+// A patch which was applied to the source tree of a go version
+type AppliedPatch struct {
+	Name   string `json:"name"`
+	Sha256 string `json:"sha256"`
+}
+
+// Returns the patch files to apply for the go version. Patches kept in the
+// per version patches directory (~/.gvm/patches/go1.9) come first in lexical
+// order, followed by the ones for the variant (~/.gvm/patches/go1.9@variant)
+// and then the ones provided explicitly by the user.
+func CollectPatches(paths utils.Paths, goVersion string, extra []string) ([]string, error) {
+	patches := make([]string, 0)
+
+	dirs := []string{goVersion}
+	if release, variant := utils.SplitGoName(goVersion); variant != "" {
+		dirs = []string{release, goVersion}
+	}
+	for _, dir := range dirs {
+		dirPatches, err := listPatchesDir(paths.Join(utils.GVM_PATCHES_DIRNAME, dir))
+		if err != nil {
+			return patches, err
+		}
+		patches = append(patches, dirPatches...)
+	}
+
+	for _, p := range extra {
+		path, err := filepath.Abs(p)
+		if err != nil {
+			return patches, err
+		}
+		if !utils.CheckIfAlreadyExist(path) {
+			return patches, fmt.Errorf("Patch file %s does not exist", p)
+		}
+		patches = append(patches, path)
+	}
+	return patches, nil
+}
+
+// List the patch files present in the patches directory in lexical order
+func listPatchesDir(patchesDir string) ([]string, error) {
+	patches := make([]string, 0)
+	if !utils.CheckIfAlreadyExist(patchesDir) {
+		return patches, nil
+	}
+
+	files, err := ioutil.ReadDir(patchesDir)
+	if err != nil {
+		return patches, err
+	}
+	for _, f := range files {
+		if !f.IsDir() {
+			patches = append(patches, filepath.Join(patchesDir, f.Name()))
+		}
+	}
+	sort.Strings(patches)
+	return patches, nil
+}
+
+// Apply the unified diffs to the extracted source tree of the go version in order.
+// Each patch is first tried with a dry run so a patch with a failing hunk is never
+// partially applied, the error returned contains the output of patch for it. The
+// source tree is removed if a patch fails, so no half patched tree is left behind.
+func ApplyPatches(paths utils.Paths, goVersion string, patches []string) (applied []AppliedPatch, err error) {
+	applied = make([]AppliedPatch, 0)
+	goVerDir := paths.Join(utils.GVM_GOS_DIRNAME, goVersion)
+	defer func() {
+		if err != nil {
+			os.RemoveAll(goVerDir)
+		}
+	}()
+
+	for _, p := range patches {
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return applied, err
+		}
+
+		if out, err := runPatch(goVerDir, p, true); err != nil {
+			return applied, fmt.Errorf("Patch %s does not apply : %v\n%s", filepath.Base(p), err, out)
+		}
+		if out, err := runPatch(goVerDir, p, false); err != nil {
+			return applied, fmt.Errorf("Error while applying patch %s : %v\n%s", filepath.Base(p), err, out)
+		}
+
+		sum := sha256.Sum256(content)
+		applied = append(applied, AppliedPatch{
+			Name:   filepath.Base(p),
+			Sha256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return applied, nil
+}
+
+func runPatch(dir string, patchFile string, dryRun bool) (string, error) {
+	args := []string{"-p1", "--forward", "--batch", "-d", dir, "-i", patchFile}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+
+	out, err := exec.Command("patch", args...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+const patchedFile = `package main
+
+func main() {
+	println("hello")
+}
+`
+
+// Changes the greeting of the fixture
+const goodPatch = `--- a/src/main.go
++++ b/src/main.go
+@@ -1,5 +1,5 @@
+ package main
+ 
+ func main() {
+-	println("hello")
++	println("hello, gvm")
+ }
+`
+
+// Adds a file and then changes a line which is not in the fixture
+const failingPatch = `--- /dev/null
++++ b/src/added.go
+@@ -0,0 +1 @@
++package main
+--- a/src/main.go
++++ b/src/main.go
+@@ -1,5 +1,5 @@
+ package main
+ 
+ func main() {
+-	println("bye")
++	println("bye, gvm")
+ }
+`
+
+// Returns the paths of a temporary root holding the source tree of go1.9, with
+// the patches written to it, which is removed when the returned function is called.
+func patchFixture(t *testing.T, patches map[string]string) (utils.Paths, func()) {
+	if _, err := exec.LookPath("patch"); err != nil {
+		t.Skip("patch is not installed")
+	}
+	root, err := ioutil.TempDir("", "gvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(root) }
+
+	src := filepath.Join(root, utils.GVM_GOS_DIRNAME, "go1.9", "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "main.go"), []byte(patchedFile), 0644); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	patchesDir := filepath.Join(root, utils.GVM_PATCHES_DIRNAME, "go1.9")
+	if err := os.MkdirAll(patchesDir, 0755); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	for name, content := range patches {
+		if err := ioutil.WriteFile(filepath.Join(patchesDir, name), []byte(content), 0644); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	return utils.Paths{Root: root, Pkgset: utils.GVM_PKGSET_NAME}, cleanup
+}
+
+func TestCollectPatches(t *testing.T) {
+	paths, cleanup := patchFixture(t, map[string]string{"2-second.patch": goodPatch, "1-first.patch": goodPatch})
+	defer cleanup()
+	extra := paths.Join("extra.patch")
+	if err := ioutil.WriteFile(extra, []byte(goodPatch), 0644); err != nil {
+		t.Fatal(err)
+	}
+	variantDir := paths.Join(utils.GVM_PATCHES_DIRNAME, "go1.9@race")
+	if err := os.MkdirAll(variantDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(variantDir, "0-variant.patch"), []byte(goodPatch), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patches, err := CollectPatches(paths, "go1.9", []string{extra})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchesDir := paths.Join(utils.GVM_PATCHES_DIRNAME, "go1.9")
+	expected := []string{
+		filepath.Join(patchesDir, "1-first.patch"),
+		filepath.Join(patchesDir, "2-second.patch"),
+		extra,
+	}
+	if !reflect.DeepEqual(patches, expected) {
+		t.Errorf("collected %v, expected %v", patches, expected)
+	}
+
+	// Patches of the release come before the ones of the variant
+	patches, err = CollectPatches(paths, "go1.9@race", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{
+		filepath.Join(patchesDir, "1-first.patch"),
+		filepath.Join(patchesDir, "2-second.patch"),
+		filepath.Join(variantDir, "0-variant.patch"),
+	}
+	if !reflect.DeepEqual(patches, expected) {
+		t.Errorf("collected %v for the variant, expected %v", patches, expected)
+	}
+
+	if _, err := CollectPatches(paths, "go1.9", []string{paths.Join("missing.patch")}); err == nil {
+		t.Error("a missing patch file was collected")
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	paths, cleanup := patchFixture(t, map[string]string{"good.patch": goodPatch})
+	defer cleanup()
+	patch := paths.Join(utils.GVM_PATCHES_DIRNAME, "go1.9", "good.patch")
+
+	applied, err := ApplyPatches(paths, "go1.9", []string{patch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(goodPatch))
+	expected := []AppliedPatch{{Name: "good.patch", Sha256: hex.EncodeToString(sum[:])}}
+	if !reflect.DeepEqual(applied, expected) {
+		t.Errorf("applied %+v, expected %+v", applied, expected)
+	}
+	content, err := ioutil.ReadFile(paths.Join(utils.GVM_GOS_DIRNAME, "go1.9", "src", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "hello, gvm") {
+		t.Errorf("source was not patched :\n%s", content)
+	}
+}
+
+func TestApplyPatchesFailure(t *testing.T) {
+	paths, cleanup := patchFixture(t, map[string]string{"good.patch": goodPatch, "failing.patch": failingPatch})
+	defer cleanup()
+	patchesDir := paths.Join(utils.GVM_PATCHES_DIRNAME, "go1.9")
+	goVerDir := paths.Join(utils.GVM_GOS_DIRNAME, "go1.9")
+
+	// The dry run refuses the patch before its first file is added
+	if out, err := runPatch(goVerDir, filepath.Join(patchesDir, "failing.patch"), true); err == nil {
+		t.Errorf("dry run of a failing patch succeeded :\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(goVerDir, "src", "added.go")); !os.IsNotExist(err) {
+		t.Error("the dry run applied a part of the patch")
+	}
+
+	applied, err := ApplyPatches(paths, "go1.9", []string{
+		filepath.Join(patchesDir, "good.patch"),
+		filepath.Join(patchesDir, "failing.patch"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "Patch failing.patch does not apply") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(applied) != 1 || applied[0].Name != "good.patch" {
+		t.Errorf("applied %+v before the failing patch", applied)
+	}
+	if _, err := os.Stat(goVerDir); !os.IsNotExist(err) {
+		t.Error("the half patched source tree was not removed")
+	}
+}
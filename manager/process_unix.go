@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package manager
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Start the command in a new process group, which is led by the command
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Kill the process group of the started command, along with the processes the
+// command started in it.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
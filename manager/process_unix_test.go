@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRunCommandKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The background sleep keeps the output open unless it is killed with the shell
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30")
+	start := time.Now()
+	if err := runCommand(ctx, cmd, ioutil.Discard); err == nil {
+		t.Error("a killed command did not fail")
+	}
+	if elapsed := time.Since(start); elapsed >= outputDelay {
+		t.Errorf("command returned after %s, its process group was not killed", elapsed)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package manager
+
+import (
+	"os/exec"
+)
+
+// Process groups are not used on windows, the command is started as it is
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Kill the started command, the processes it started are not killed on windows
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
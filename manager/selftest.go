@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Maximum number of failure lines recorded for a self test
+const maxSelfTestFailures = 50
+
+// Result of running the go test suite for a built go tree
+type SelfTestResult struct {
+	Passed   bool      `json:"passed"`
+	Time     time.Time `json:"time"`
+	Duration string    `json:"duration"`
+	Failures []string  `json:"failures,omitempty"`
+}
+
+// Run the go test suite (run.bash) for the already built go version, the output is
+// logged to a new self test log for the version while the failing tests are captured
+// in the result. An error is only returned if the test suite could not be run at all
+// or it was killed because ctx is done.
+func RunSelfTest(ctx context.Context, paths utils.Paths, log *logger.Logger, goVersion string, opts BuildOptions, quiet bool) (*SelfTestResult, error) {
+	env, err := CreateCompilationEnv(paths, goVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	goSrcDir := paths.Join(utils.GVM_GOS_DIRNAME, goVersion, "src")
+
+	cmd := exec.Command("./run.bash", "--no-rebuild")
+	cmd.Dir = goSrcDir
+	cmd.Env = env
+
+	start := time.Now()
+	logFile, err := runLogged(ctx, cmd, paths, log, goVersion, SELFTEST_LOG, quiet)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, err
+	}
+
+	result := &SelfTestResult{
+		Passed:   err == nil,
+		Time:     start,
+		Duration: time.Since(start).Round(time.Second).String(),
+	}
+	if f, e := os.Open(logFile); e == nil {
+		result.Failures = collectTestFailures(f)
+		f.Close()
+	}
+	return result, nil
+}
+
+// Collect the lines reporting a failing test or package from the test output
+func collectTestFailures(output io.Reader) []string {
+	failures := make([]string, 0)
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() && len(failures) < maxSelfTestFailures {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "--- FAIL") || strings.HasPrefix(line, "FAIL") {
+			failures = append(failures, line)
+		}
+	}
+	return failures
+}
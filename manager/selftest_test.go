@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectTestFailures(t *testing.T) {
+	output := `##### Testing packages.
+ok  	archive/tar	0.123s
+--- FAIL: TestDial (0.01s)
+    dial_test.go:42: connection refused
+FAIL
+FAIL	net	1.234s
+ok  	os	0.456s
+    --- FAIL: TestLookup/ipv6 (0.00s)
+`
+	expected := []string{
+		"--- FAIL: TestDial (0.01s)",
+		"FAIL",
+		"FAIL\tnet\t1.234s",
+		"--- FAIL: TestLookup/ipv6 (0.00s)",
+	}
+	if failures := collectTestFailures(strings.NewReader(output)); !reflect.DeepEqual(failures, expected) {
+		t.Errorf("collected %q, expected %q", failures, expected)
+	}
+
+	var many string
+	for i := 0; i < 2*maxSelfTestFailures; i++ {
+		many += fmt.Sprintf("--- FAIL: Test%d (0.00s)\n", i)
+	}
+	if failures := collectTestFailures(strings.NewReader(many)); len(failures) != maxSelfTestFailures {
+		t.Errorf("collected %d failures, expected at most %d", len(failures), maxSelfTestFailures)
+	}
+}
@@ -0,0 +1,47 @@
+package network
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Suffix of the url of the published sha256 of an official binary distribution,
+// added to the url of the archive.
+const CHECKSUM_SUFFIX = ".sha256"
+
+// Returns the sha256 published for the archive at url, which is the hex encoded
+// digest found at the url followed by CHECKSUM_SUFFIX.
+func PublishedSha256(ctx context.Context, client *http.Client, url string) (string, error) {
+	checksumUrl := url + CHECKSUM_SUFFIX
+	req, err := http.NewRequest("GET", checksumUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", statusError(checksumUrl, res)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("No checksum found at %s", checksumUrl)
+	}
+	sum := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != 32 {
+		return "", fmt.Errorf("Invalid checksum %q found at %s", fields[0], checksumUrl)
+	}
+	return sum, nil
+}
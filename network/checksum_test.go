@@ -0,0 +1,52 @@
+package network
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublishedSha256(t *testing.T) {
+	const sum = "474a468fadfaff01e38e9ab6ababdf73a9e52632c1f4af416a477c47fc563ea2"
+	checksums := map[string]string{
+		"/go1.9.tar.gz.sha256":   sum + "\n",
+		"/go1.10.tar.gz.sha256":  "474A468FADFAFF01E38E9AB6ABABDF73A9E52632C1F4AF416A477C47FC563EA2  go1.10.tar.gz\n",
+		"/invalid.tar.gz.sha256": "not a checksum\n",
+		"/empty.tar.gz.sha256":   "",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := checksums[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		archive string
+		invalid bool
+	}{
+		{archive: "go1.9.tar.gz"},
+		{archive: "go1.10.tar.gz"},
+		{archive: "invalid.tar.gz", invalid: true},
+		{archive: "empty.tar.gz", invalid: true},
+		{archive: "missing.tar.gz", invalid: true},
+	}
+	for _, test := range tests {
+		found, err := PublishedSha256(context.Background(), server.Client(), server.URL+"/"+test.archive)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("expected an error for %s, got %s", test.archive, found)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("checksum of %s : %v", test.archive, err)
+		} else if found != sum {
+			t.Errorf("checksum of %s is %s, expected %s", test.archive, found, sum)
+		}
+	}
+}
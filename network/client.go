@@ -0,0 +1,47 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Pool of the root certificates trusted for TLS connections, nil to use the
+// system pool. It is set when an extra CA bundle is configured.
+var rootCAs *x509.CertPool
+
+// Trust the certificates in the PEM encoded bundle in addition to the system ones,
+// useful when the connections are intercepted by a proxy with its own CA.
+func SetCABundle(path string) error {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Error while reading CA bundle : %v", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("No certificates found in CA bundle %s", path)
+	}
+	rootCAs = pool
+	return nil
+}
+
+// Returns a new HTTP client honoring the proxy environment variables (HTTPS_PROXY,
+// NO_PROXY ...) and the configured CA bundle. The certificate of the server is
+// not verified if skipTls is set.
+func NewClient(skipTls bool) *http.Client {
+	transport := &http.Transport{
+		Proxy:        http.ProxyFromEnvironment,
+		MaxIdleConns: 10,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: skipTls,
+			RootCAs:            rootCAs,
+		},
+	}
+	return &http.Client{Transport: transport}
+}
@@ -1,45 +1,100 @@
 package network
 
 import (
-	"os"
-	"os/signal"
-	"path/filepath"
-	"syscall"
+	"context"
+	"errors"
+	"net/http"
+	"time"
 
 	"github.com/fristonio/gvm/logger"
 	"github.com/fristonio/gvm/utils"
 )
 
-var log *logger.Logger = logger.New(os.Stdout)
+// Error returned when the file or its parts are already present in the downloads directory
+var ErrPreviousDownload = errors.New("File already exists in the downloads directory")
 
-// Download the contents of the given URL
-func Download(url string, skiptls bool, conn int64, forceClean bool) error {
-	var err error
-	// We are taking maximum no of concurrent downloads to be conn.
+// Options for downloading a file
+type DownloadOptions struct {
+	// Skip the verification of the TLS certificate of the server
+	SkipTls bool
+	// Client for the requests, a client from NewClient is used when nil
+	Client *http.Client
+	// Maximum number of concurrent connections
+	Conn int64
+	// Clear a previous download of the file instead of failing
+	ForceClean bool
+	// Number of times a part is retried before failing the download
+	Retries int
+	// Maximum bytes per second for the download across all the connections, 0 for no limit
+	LimitRate int64
+	// Directory the file is downloaded to, defaults to the downloads directory of
+	// utils.GVM_ROOT_DIR
+	Dir string
+	// Logger for the progress of the download, defaults to logger.Log
+	Log *logger.Logger
+}
+
+// Returns the client for the requests of the download
+func (o DownloadOptions) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return NewClient(o.SkipTls)
+}
 
-	var files []string
+// Returns the directory the file is downloaded to
+func (o DownloadOptions) dir() string {
+	if o.Dir != "" {
+		return o.Dir
+	}
+	return utils.DefaultPaths().Join(utils.GVM_DOWNLOAD_DIR)
+}
 
-	// Create a singal channel to catch system interrupts
-	signal_chan := make(chan os.Signal, 1)
-	signal.Notify(signal_chan,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGTERM,
-		syscall.SIGQUIT)
+// Returns the logger for the progress of the download
+func (o DownloadOptions) log() *logger.Logger {
+	if o.Log != nil {
+		return o.Log
+	}
+	return logger.Log
+}
 
-	var isInterrupted = false
+// Download the contents of the given location to the downloads directory, the
+// location can be any which is supported by a Fetcher. The download is stopped
+// and its partial file removed once ctx is done.
+func Download(ctx context.Context, location string, opts DownloadOptions) error {
+	fetcher, err := NewFetcher(location)
+	if err != nil {
+		return err
+	}
+	return fetcher.Fetch(ctx, opts)
+}
 
-	doneChan := make(chan bool, conn)
-	fileChan := make(chan string, conn)
+// Download the contents of the given URL using multiple connections
+func (f *httpFetcher) Fetch(ctx context.Context, opts DownloadOptions) error {
+	url := f.url
+	log := opts.log()
+	var err error
+	// We are taking maximum no of concurrent downloads to be conn.
+	conn := opts.Conn
+
+	// Error of the first part which failed, the download is stopped once a part fails
+	var partErr error
+	// Stops the parts still downloading when ctx is done or a part failed
+	partsCtx, stopParts := context.WithCancel(ctx)
+	defer stopParts()
+
+	doneChan := make(chan bool, 1)
 	errorChan := make(chan error, 1)
-	interruptChan := make(chan bool, conn)
+	progressChan := make(chan Progress, conn*4)
 
-	var downloader *HttpDownloader
-	downloader = NewDownloader(url, conn, true)
+	downloader, err := NewDownloader(ctx, url, opts)
+	if err != nil {
+		return err
+	}
 	// Verfiy and clean already downloaded files in downloads directory.
 	if err := downloader.VerifyDownloadDestination(); err != nil {
 		log.Errorf("An error occured while verifying download destination : %v", err)
-		if forceClean {
+		if opts.ForceClean {
 			if e := downloader.ClearPreviousDownload(); e != nil {
 				return e
 			}
@@ -48,44 +103,60 @@ func Download(url string, skiptls bool, conn int64, forceClean bool) error {
 		}
 	}
 
+	progress := newProgressDisplay(log, downloader.PartSizes())
+	start := time.Now()
+
 	// Start a goroutine for the download
-	go downloader.Do(doneChan, fileChan, errorChan, interruptChan)
+	go downloader.Do(partsCtx, doneChan, errorChan, progressChan)
 
 	for {
 		select {
-		case <-signal_chan:
-			// send parts number of interrupt for each routine
-			isInterrupted = true
-			for i := int64(0); i < conn; i++ {
-				interruptChan <- true
-			}
-		case file := <-fileChan:
-			files = append(files, file)
+		case p := <-progressChan:
+			progress.Update(p)
 		case err := <-errorChan:
-			log.Errorf("%v", err)
-			panic(err) //maybe need better style
+			log.Debugf("%v", err)
+			if partErr == nil {
+				// Stop the parts still downloading
+				partErr = utils.WrapError(utils.NETWORK_ERROR, err, "Error while downloading "+url)
+				stopParts()
+			}
 		case <-doneChan:
+			// Drain the progress reported before the parts finished
+			for len(progressChan) > 0 {
+				progress.Update(<-progressChan)
+			}
+			progress.Finish()
 			// Check if the download was successful or it closed due to some  interrupt
-			if isInterrupted {
+			if ctx.Err() != nil {
 				// Download not finished, interrupt occured. Catch it here
-				// As of now we clear the partial downloads when an interrupt occurs
-				// But we can give a mechanism to resume the download by saving the state of
-				// of current partial downloads.
+				// As of now we clear the partial download when an interrupt occurs,
+				// only a download which failed is kept to be resumed later.
 				log.Warn("Download was interrupted ....")
 				log.Warn("Cleaning things up.")
-				err = utils.RemoveFilePartials(url)
-				utils.FatalCheck(err, "Error occured while removing partial downloads")
-				return nil
-			} else {
-				// Download finished successfully, now join the partial downloads to a single file
-				log.Info("Download finished, working on joining partials...")
-				err = utils.JoinFilePartials(files, filepath.Base(url))
-				utils.FatalCheck(err, "Partial Join of files failed")
-				utils.RemoveFilePartials(url)
-				utils.FatalCheck(err, "Exitting....")
-				return nil
+				if err = utils.RemoveFilePartials(opts.dir(), url); err != nil {
+					log.Errorf("Error occured while removing partial downloads : %v", err)
+				}
+				return utils.WrapError(utils.ABORTED_ERROR, ctx.Err(), "Download of "+url+" was interrupted")
+			}
+			if partErr != nil && downloader.RangeIgnored() && conn > 1 {
+				log.Warn("The server does not support partial downloads, downloading with a single connection")
+				opts.Conn = 1
+				opts.ForceClean = true
+				return f.Fetch(ctx, opts)
+			}
+			if partErr != nil {
+				// Keep the partial download so that it can be resumed later
+				if e := downloader.SaveState(); e != nil {
+					log.Warnf("Could not save the state of the download : %v", e)
+				}
+				return partErr
 			}
+			// Download finished successfully, move the download file to its final path
+			log.WithFields(logger.Fields{
+				"url":      url,
+				"duration": time.Since(start).String(),
+			}).Info("Download finished...")
+			return downloader.Finish()
 		}
 	}
-	return nil
 }
@@ -0,0 +1,159 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+// Fetcher fetches a file from its location to the downloads directory under
+// gvm root, keeping the name of the file, or opens it to stream its contents.
+type Fetcher interface {
+	Fetch(ctx context.Context, opts DownloadOptions) error
+	// Returns the contents of the file and its size, -1 if it is not known
+	Open(ctx context.Context, opts DownloadOptions) (io.ReadCloser, int64, error)
+}
+
+// Fetcher for http:// and https:// urls
+type httpFetcher struct {
+	url string
+}
+
+// Fetcher for file:// urls and plain paths, like a mounted share
+type localFetcher struct {
+	path string
+}
+
+// Returns the fetcher for the location selected by the scheme of the location,
+// http(s):// and file:// urls and plain paths are supported.
+func NewFetcher(location string) (Fetcher, error) {
+	if filepath.IsAbs(location) {
+		return &localFetcher{path: location}, nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid location %s : %v", location, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpFetcher{url: location}, nil
+	case "file":
+		// The path of file://mirror/go1.9.tar.gz is /go1.9.tar.gz, mirror being its host
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("Unsupported host %s for %s, use file:///path for a local path", u.Host, location)
+		}
+		return &localFetcher{path: filepath.FromSlash(u.Path)}, nil
+	case "":
+		return &localFetcher{path: location}, nil
+	}
+	return nil, utils.NewError(utils.USAGE_ERROR, "Unsupported scheme %s for %s", u.Scheme, location)
+}
+
+// Returns the location of the archive in a mirror, the mirror is any location
+// supported by a Fetcher containing the archives with the same names as upstream,
+// like go1.9.tar.gz for the source or go1.9.linux-amd64.tar.gz for binaries.
+func MirrorLocation(mirror string, archive string) string {
+	if strings.Contains(mirror, "://") {
+		return strings.TrimSuffix(mirror, "/") + "/" + archive
+	}
+	return filepath.Join(mirror, archive)
+}
+
+// Open the file at the path
+func (f *localFetcher) Open(ctx context.Context, opts DownloadOptions) (io.ReadCloser, int64, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil, 0, utils.NewError(utils.NOT_FOUND_ERROR, "%s does not exist", f.path)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, 0, fmt.Errorf("%s is a directory", f.path)
+	}
+	return file, info.Size(), nil
+}
+
+// Copy the file to the downloads directory, the partial copy is removed if ctx
+// is done before the copy completes.
+func (f *localFetcher) Fetch(ctx context.Context, opts DownloadOptions) error {
+	log := opts.log()
+	log.Debugf("New path for fetching : %s", f.path)
+	downloadsDir := opts.dir()
+	destination := filepath.Join(downloadsDir, filepath.Base(f.path))
+	partial := destination + ".partial"
+
+	if utils.CheckIfAlreadyExist(destination) || utils.CheckIfAlreadyExist(partial) {
+		if !opts.ForceClean {
+			return ErrPreviousDownload
+		}
+		if err := utils.RemoveAll([]string{destination, partial}); err != nil {
+			return err
+		}
+	}
+
+	src, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return utils.NewError(utils.NOT_FOUND_ERROR, "%s does not exist", f.path)
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", f.path)
+	}
+	log.WithField("bytes", info.Size()).Infof("Download Size : %s", utils.MemoryBytesToString(info.Size()))
+
+	if err = utils.MkdirIfNotExist(downloadsDir); err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	progress := newProgressDisplay(log, []int64{info.Size()})
+	for {
+		if ctx.Err() != nil {
+			progress.Finish()
+			dst.Close()
+			os.Remove(partial)
+			return utils.WrapError(utils.ABORTED_ERROR, ctx.Err(), "Copy of "+f.path+" was interrupted")
+		}
+		written, err := io.CopyN(dst, src, copyChunkSize)
+		progress.Update(Progress{Part: 0, Bytes: written})
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			dst.Close()
+			os.Remove(partial)
+			return err
+		}
+	}
+	progress.Finish()
+
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partial, destination)
+}
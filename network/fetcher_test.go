@@ -0,0 +1,37 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewFetcher(t *testing.T) {
+	tests := []struct {
+		location string
+		expected Fetcher
+	}{
+		{"https://dl.google.com/go/go1.9.tar.gz", &httpFetcher{url: "https://dl.google.com/go/go1.9.tar.gz"}},
+		{"http://mirror.local/go1.9.tar.gz", &httpFetcher{url: "http://mirror.local/go1.9.tar.gz"}},
+		{"file:///srv/mirror/go1.9.tar.gz", &localFetcher{path: "/srv/mirror/go1.9.tar.gz"}},
+		{"file://localhost/srv/mirror/go1.9.tar.gz", &localFetcher{path: "/srv/mirror/go1.9.tar.gz"}},
+		{"/srv/mirror/go1.9.tar.gz", &localFetcher{path: "/srv/mirror/go1.9.tar.gz"}},
+		{"mirror/go1.9.tar.gz", &localFetcher{path: "mirror/go1.9.tar.gz"}},
+		{"file://mirror/go1.9.tar.gz", nil},
+		{"ftp://mirror.local/go1.9.tar.gz", nil},
+	}
+
+	for _, test := range tests {
+		fetcher, err := NewFetcher(test.location)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s : expected an error, got %+v", test.location, fetcher)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : unexpected error %v", test.location, err)
+		} else if !reflect.DeepEqual(fetcher, test.expected) {
+			t.Errorf("%s : fetcher %+v, expected %+v", test.location, fetcher, test.expected)
+		}
+	}
+}
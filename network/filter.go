@@ -0,0 +1,161 @@
+package network
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Number of minor releases of go supported at a time, each major release is
+// supported until there are two newer major releases.
+const SUPPORTED_MINORS = 2
+
+// Matches the name of a go release capturing the parts of its version
+var versionRegexp = regexp.MustCompile(`^go(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:(beta|rc)(\d+))?$`)
+
+// Version of a go release parsed from its name, go1.21.0 and go1.21 are the same
+// version and prereleases come before the release like go1.21beta1 < go1.21rc1 < go1.21.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Kind of the prerelease, beta or rc, empty for a stable release
+	Pre    string
+	PreNum int
+}
+
+// Parse the version of the go release with the name
+func ParseVersion(name string) (Version, error) {
+	matches := versionRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return Version{}, fmt.Errorf("%s is not the name of a go release like go1.21.5", name)
+	}
+
+	numbers := make([]int, 0, 4)
+	for _, match := range []string{matches[1], matches[2], matches[3], matches[5]} {
+		n, _ := strconv.Atoi(match)
+		numbers = append(numbers, n)
+	}
+	return Version{
+		Major:  numbers[0],
+		Minor:  numbers[1],
+		Patch:  numbers[2],
+		Pre:    matches[4],
+		PreNum: numbers[3],
+	}, nil
+}
+
+// Reports if the version comes before the other one
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	if v.Patch != o.Patch {
+		return v.Patch < o.Patch
+	}
+	if v.Pre != o.Pre {
+		// A stable release comes after its prereleases, beta before rc
+		return v.Pre != "" && (o.Pre == "" || v.Pre < o.Pre)
+	}
+	return v.PreNum < o.PreNum
+}
+
+// Returns the minor release of the version, like 1.21 for go1.21.5
+func (v Version) MinorName() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Filters for the releases of go, a release is kept only if it matches all of them
+type ReleaseFilter struct {
+	// Keep only the stable releases
+	Stable bool
+	// Keep only the betas and release candidates
+	Prerelease bool
+	// Keep only the releases of the minor release, like 1.21
+	Minor string
+	// Keep only the releases from this one on, like go1.18
+	Since string
+	// Keep only the latest of the kept releases of each minor release
+	LatestPerMinor bool
+	// Keep only the releases of the minor releases which are supported, the
+	// SUPPORTED_MINORS most recent ones with a stable release.
+	Supported bool
+}
+
+// Check that the filters can be combined and that the releases they refer to are valid
+func (f ReleaseFilter) Validate() error {
+	if f.Stable && f.Prerelease {
+		return fmt.Errorf("only one of stable and prerelease releases can be kept")
+	}
+	if minor := strings.TrimPrefix(f.Minor, "go"); minor != "" {
+		v, err := ParseVersion("go" + minor)
+		if err != nil || v.MinorName() != minor {
+			return fmt.Errorf("%s is not a minor release of go like 1.21", f.Minor)
+		}
+	}
+	if f.Since != "" {
+		if _, err := ParseVersion("go" + strings.TrimPrefix(f.Since, "go")); err != nil {
+			return fmt.Errorf("%s is not a release of go like go1.18", f.Since)
+		}
+	}
+	return nil
+}
+
+// Returns the releases matching the filter sorted by version, the releases whose
+// version can not be parsed are left out.
+func FilterReleases(releases []Release, filter ReleaseFilter) ([]Release, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	minor := strings.TrimPrefix(filter.Minor, "go")
+	since, _ := ParseVersion("go" + strings.TrimPrefix(filter.Since, "go"))
+
+	versions := make(map[string]Version)
+	sorted := make([]Release, 0, len(releases))
+	for _, release := range releases {
+		if v, err := ParseVersion(release.Name); err == nil {
+			versions[release.Name] = v
+			sorted = append(sorted, release)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return versions[sorted[i].Name].Less(versions[sorted[j].Name])
+	})
+
+	supported := make(map[string]bool)
+	for i := len(sorted) - 1; i >= 0 && len(supported) < SUPPORTED_MINORS; i-- {
+		if v := versions[sorted[i].Name]; v.Pre == "" {
+			supported[v.MinorName()] = true
+		}
+	}
+
+	kept := make([]Release, 0, len(sorted))
+	for _, release := range sorted {
+		v := versions[release.Name]
+		switch {
+		case filter.Stable && v.Pre != "",
+			filter.Prerelease && v.Pre == "",
+			minor != "" && v.MinorName() != minor,
+			filter.Since != "" && v.Less(since),
+			filter.Supported && !supported[v.MinorName()]:
+			continue
+		}
+		kept = append(kept, release)
+	}
+
+	if filter.LatestPerMinor {
+		latest := make([]Release, 0, len(kept))
+		for i, release := range kept {
+			if i+1 == len(kept) || versions[kept[i+1].Name].MinorName() != versions[release.Name].MinorName() {
+				latest = append(latest, release)
+			}
+		}
+		kept = latest
+	}
+	return kept, nil
+}
@@ -0,0 +1,149 @@
+package network
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVersionOrder(t *testing.T) {
+	// Each version comes before the next one
+	ordered := []string{
+		"go1", "go1.2rc2", "go1.2", "go1.9", "go1.9.2rc2", "go1.9.2", "go1.10",
+		"go1.21beta1", "go1.21rc1", "go1.21rc2", "go1.21", "go1.21.1", "go2",
+	}
+	for i := 0; i+1 < len(ordered); i++ {
+		v, err := ParseVersion(ordered[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		next, err := ParseVersion(ordered[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !v.Less(next) || next.Less(v) {
+			t.Errorf("%s should come before %s", ordered[i], ordered[i+1])
+		}
+	}
+
+	// go1.21 and go1.21.0 are the same version
+	v, _ := ParseVersion("go1.21")
+	same, _ := ParseVersion("go1.21.0")
+	if v != same {
+		t.Errorf("go1.21 is %+v while go1.21.0 is %+v", v, same)
+	}
+
+	for _, name := range []string{"1.21", "go1.21.", "go1.21rc", "go1.21alpha1", "gotip"} {
+		if _, err := ParseVersion(name); err == nil {
+			t.Errorf("%s was parsed as a version", name)
+		}
+	}
+}
+
+// Returns the releases with the names
+func releases(names ...string) []Release {
+	releases := make([]Release, 0, len(names))
+	for _, name := range names {
+		releases = append(releases, Release{Name: name, DownloadUrl: name + ".tar.gz"})
+	}
+	return releases
+}
+
+func TestFilterReleases(t *testing.T) {
+	// Listed out of order like the tags of the repository
+	all := releases(
+		"go1.10", "go1.9", "go1.9.2", "go1.9.2rc2", "go1.20", "go1.20.1",
+		"go1.21rc1", "go1.21beta1", "go1.21.0", "go1.21.1", "go1.22rc1", "weekly.2012-03-27",
+	)
+
+	tests := []struct {
+		name     string
+		filter   ReleaseFilter
+		expected string
+	}{
+		{
+			name:     "no filter",
+			expected: "go1.9 go1.9.2rc2 go1.9.2 go1.10 go1.20 go1.20.1 go1.21beta1 go1.21rc1 go1.21.0 go1.21.1 go1.22rc1",
+		},
+		{
+			name:     "stable",
+			filter:   ReleaseFilter{Stable: true},
+			expected: "go1.9 go1.9.2 go1.10 go1.20 go1.20.1 go1.21.0 go1.21.1",
+		},
+		{
+			name:     "prerelease",
+			filter:   ReleaseFilter{Prerelease: true},
+			expected: "go1.9.2rc2 go1.21beta1 go1.21rc1 go1.22rc1",
+		},
+		{
+			name:     "minor",
+			filter:   ReleaseFilter{Minor: "1.9"},
+			expected: "go1.9 go1.9.2rc2 go1.9.2",
+		},
+		{
+			name:     "since a release excludes its prereleases",
+			filter:   ReleaseFilter{Since: "go1.21"},
+			expected: "go1.21.0 go1.21.1 go1.22rc1",
+		},
+		{
+			name:     "since a prerelease",
+			filter:   ReleaseFilter{Since: "1.21rc1"},
+			expected: "go1.21rc1 go1.21.0 go1.21.1 go1.22rc1",
+		},
+		{
+			name:     "stable since a prerelease",
+			filter:   ReleaseFilter{Since: "go1.9.2rc1", Stable: true},
+			expected: "go1.9.2 go1.10 go1.20 go1.20.1 go1.21.0 go1.21.1",
+		},
+		{
+			name:     "supported when the newest minor has only release candidates",
+			filter:   ReleaseFilter{Supported: true},
+			expected: "go1.20 go1.20.1 go1.21beta1 go1.21rc1 go1.21.0 go1.21.1",
+		},
+		{
+			name:     "latest per minor",
+			filter:   ReleaseFilter{LatestPerMinor: true},
+			expected: "go1.9.2 go1.10 go1.20.1 go1.21.1 go1.22rc1",
+		},
+		{
+			name:     "latest stable per supported minor",
+			filter:   ReleaseFilter{Stable: true, Supported: true, LatestPerMinor: true},
+			expected: "go1.20.1 go1.21.1",
+		},
+	}
+	for _, test := range tests {
+		filtered, err := FilterReleases(all, test.filter)
+		if err != nil {
+			t.Errorf("%s : %v", test.name, err)
+			continue
+		}
+		names := make([]string, 0, len(filtered))
+		for _, release := range filtered {
+			names = append(names, release.Name)
+		}
+		if expected := strings.Fields(test.expected); !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s : filtered %v, expected %v", test.name, names, expected)
+		}
+	}
+}
+
+func TestReleaseFilterValidate(t *testing.T) {
+	invalid := []ReleaseFilter{
+		{Stable: true, Prerelease: true},
+		{Minor: "1.21.1"},
+		{Minor: "1"},
+		{Minor: "latest"},
+		{Since: "1.x"},
+	}
+	for _, filter := range invalid {
+		if err := filter.Validate(); err == nil {
+			t.Errorf("filter %+v is valid", filter)
+		}
+		if _, err := FilterReleases(releases("go1.21"), filter); err == nil {
+			t.Errorf("releases were filtered with %+v", filter)
+		}
+	}
+	if err := (ReleaseFilter{Minor: "go1.21", Since: "go1.20rc1"}).Validate(); err != nil {
+		t.Errorf("valid filter refused : %v", err)
+	}
+}
@@ -1,15 +1,22 @@
 package network
 
 import (
-	"crypto/tls"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/fristonio/gvm/logger"
 	"github.com/fristonio/gvm/utils"
 )
 
@@ -18,12 +25,77 @@ const (
 	CONTENT_LENGTH_HEADER = "Content-Length"
 )
 
-// PartFile Structure
+// Bytes copied to a part file in each iteration of the interruptable copy loop
+const copyChunkSize = 32 * 1024
+
+// Number of times a part is retried by default, and the bounds of the backoff
+// between two attempts.
+const (
+	DEFAULT_RETRIES = 5
+	minBackoff      = 1 * time.Second
+	maxBackoff      = 30 * time.Second
+)
+
+// Error for a part whose range was not honored by the server, which replied with
+// the whole file instead of the part.
+var errRangeIgnored = errors.New("Server ignored the range of the part and sent the whole file")
+
+// Error for a part which would fail again if retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Returns the error for a response with a failure status, a missing resource is
+// reported as a not found error and any other status as a network error.
+func statusError(url string, res *http.Response) error {
+	kind := utils.NETWORK_ERROR
+	if res.StatusCode == http.StatusNotFound {
+		kind = utils.NOT_FOUND_ERROR
+	}
+	return utils.NewError(kind, "Error while requesting %s : server responded with %s", url, res.Status)
+}
+
+// Returns the time to wait before retrying after the attempt, doubling for each
+// attempt with a random jitter of up to half of it.
+func backoff(attempt int) time.Duration {
+	wait := minBackoff << uint(attempt)
+	if wait > maxBackoff || wait <= 0 {
+		wait = maxBackoff
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait)/2+1))
+}
+
+// PartFile Structure, Written are the bytes of the part already written to the
+// download file which is used for resuming the part.
 type PartFile struct {
-	Url       string
-	Path      string
-	RangeFrom int64
-	RangeTo   int64
+	Url       string `json:"url"`
+	RangeFrom int64  `json:"range_from"`
+	RangeTo   int64  `json:"range_to"`
+	Written   int64  `json:"written"`
+}
+
+// State of an unfinished download saved along with the partial download file,
+// so that it can be resumed later.
+type downloadState struct {
+	ContentLength int64      `json:"content_length"`
+	Parts         []PartFile `json:"parts"`
+}
+
+// Writes sequentially to a file starting at an offset, allowing each part to
+// write to its own range of the same file.
+type offsetWriter struct {
+	f      *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
 }
 
 // Downloader structure - For downloading a file this is the structure
@@ -35,31 +107,67 @@ type HttpDownloader struct {
 	parts         int64
 	contentLength int64
 	skipTls       bool
+	retries       int
 	fileParts     []PartFile
+	client        *http.Client
+	limiter       *RateLimiter
+	// Directory the file is downloaded to
+	dir string
+	log *logger.Logger
+	// Set to 1 once a part got the whole file instead of its range
+	rangeIgnored int32
 }
 
-var (
-	// To get a control over client TLS
-	transport = &http.Transport{
-		MaxIdleConns:    10,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	// New HTTP client with TLS capabilities
-	client = &http.Client{Transport: transport}
-)
-
 // Initializes a downloader structure defining a download with values
 // and returns it
-func NewDownloader(url string, parts int64, skipTls bool) *HttpDownloader {
-	log.Infof("New URL for downloading : %s", url)
+func NewDownloader(ctx context.Context, url string, opts DownloadOptions) (*HttpDownloader, error) {
+	parts := opts.Conn
+	skipTls := opts.SkipTls
+	retries := opts.Retries
+	log := opts.log()
+
+	log.Debugf("New URL for downloading : %s", url)
+	if skipTls {
+		log.Warn("TLS certificate verification is disabled for the download")
+	}
+	client := opts.client()
 	req, err := http.NewRequest("HEAD", url, nil)
-	utils.FatalCheck(err, "Error while making HEAD request to source url")
+	if err != nil {
+		return nil, fmt.Errorf("Error while making HEAD request to source url : %v", err)
+	}
+	req = req.WithContext(ctx)
 
-	res, err := client.Do(req)
-	utils.FatalCheck(err, "Error while requesting the resource...")
+	var res *http.Response
+	for attempt := 0; ; attempt++ {
+		res, err = client.Do(req)
+		if err == nil && res.StatusCode < 500 {
+			break
+		}
+		if err == nil {
+			res.Body.Close()
+			err = fmt.Errorf("Server responded with %s", res.Status)
+		}
+		if ctx.Err() != nil {
+			return nil, utils.WrapError(utils.ABORTED_ERROR, ctx.Err(), "Request for "+url+" was interrupted")
+		}
+		if attempt >= retries {
+			return nil, utils.NewError(utils.NETWORK_ERROR, "Error while requesting the resource : %v", err)
+		}
+		wait := backoff(attempt)
+		log.Warnf("Error while requesting the resource : %v, retrying in %s", err, wait)
+		select {
+		case <-ctx.Done():
+			return nil, utils.WrapError(utils.ABORTED_ERROR, ctx.Err(), "Request for "+url+" was interrupted")
+		case <-time.After(wait):
+		}
+	}
+	res.Body.Close()
+	if res.StatusCode >= 400 {
+		return nil, statusError(url, res)
+	}
 
 	if res.Header.Get(ACCEPT_RANGE_HEADER) == "" {
-		log.Info("Download url does not support partial download, fallback to normal download")
+		log.Debug("Download url does not support partial download, fallback to normal download")
 		// Fallback to no part downloading
 		parts = 1
 	}
@@ -67,17 +175,19 @@ func NewDownloader(url string, parts int64, skipTls bool) *HttpDownloader {
 	//get download range
 	clen := res.Header.Get(CONTENT_LENGTH_HEADER)
 	if clen == "" {
-		log.Info("No Content-Length header recieved, fallback to normal download")
+		log.Debug("No Content-Length header recieved, fallback to normal download")
 		clen = "1"
 		parts = 1
 	}
 
-	log.Infof("Starting download with %v connections", parts)
+	log.Debugf("Starting download with %v connections", parts)
 	contentLength, err := strconv.ParseInt(clen, 10, 64)
-	utils.FatalCheck(err, "Content-Length Header value %s not valid", contentLength)
+	if err != nil {
+		return nil, fmt.Errorf("Content-Length Header value %s not valid", clen)
+	}
 
 	sizeDescrip := utils.MemoryBytesToString(contentLength)
-	log.Infof("Download Size : %s", sizeDescrip)
+	log.WithField("bytes", contentLength).Infof("Download Size : %s", sizeDescrip)
 
 	fileName := filepath.Base(url)
 	// Final downloader structure
@@ -88,10 +198,18 @@ func NewDownloader(url string, parts int64, skipTls bool) *HttpDownloader {
 		parts:         parts,
 		contentLength: contentLength,
 		skipTls:       skipTls,
+		retries:       retries,
 		fileParts:     calculateDownloadParts(int64(parts), contentLength, url),
+		client:        client,
+		dir:           opts.dir(),
+		log:           log,
 	}
 
-	return downloader
+	if opts.LimitRate > 0 {
+		log.Infof("Limiting download rate to %s/s", utils.MemoryBytesToString(opts.LimitRate))
+		downloader.limiter = NewRateLimiter(opts.LimitRate)
+	}
+	return downloader, nil
 }
 
 // Takes in the bytes to download and the no of parts and returns and array of Partial File
@@ -106,51 +224,127 @@ func calculateDownloadParts(parts int64, contentLength int64, url string) []Part
 		} else {
 			to = contentLength
 		}
+		fileParts = append(fileParts, PartFile{Url: url, RangeFrom: from, RangeTo: to})
+	}
+	return fileParts
+}
+
+// Path of the download file while the download is in progress, the parts are
+// written at their offset in this file which is renamed once all of them finish.
+// ~/.gvm/downloads/go1.9.tar.gz.partial
+func (d *HttpDownloader) partialPath() string {
+	return filepath.Join(d.dir, d.fileName+".partial")
+}
+
+// Path of the file storing the state of the parts of an unfinished download
+func (d *HttpDownloader) statePath() string {
+	return d.partialPath() + ".json"
+}
+
+// Check if the file does not already exist in the download directory, return
+// ErrPreviousDownload if it is present. An unfinished download of the same file
+// is resumed if its saved state matches the file, otherwise ErrPreviousDownload
+// is returned for it too.
+func (d *HttpDownloader) VerifyDownloadDestination() error {
+	goSourcePath := filepath.Join(d.dir, d.fileName)
+	if utils.CheckIfAlreadyExist(goSourcePath) {
+		return ErrPreviousDownload
+	}
 
-		file := filepath.Base(url)
-		folder := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_DOWNLOAD_DIR)
-		if err := utils.MkdirIfNotExist(folder); err != nil {
-			log.Fatalf("%v", err)
+	if utils.CheckIfAlreadyExist(d.partialPath()) {
+		if !d.loadState() {
+			return ErrPreviousDownload
 		}
+		var written int64
+		for _, part := range d.fileParts {
+			written += part.Written
+		}
+		d.log.Infof("Resuming previous download, %s already downloaded", utils.MemoryBytesToString(written))
+	}
+	return nil
+}
 
-		fname := fmt.Sprintf("%s.part%d", file, j)
-		// ~/.gvm/downloads/fname.part
-		path := filepath.Join(folder, fname)
-		fileParts = append(fileParts, PartFile{Url: url, Path: path, RangeFrom: from, RangeTo: to})
+// Load the state saved for an unfinished download, returns false if there is
+// no state or it does not match the file being downloaded.
+func (d *HttpDownloader) loadState() bool {
+	content, err := ioutil.ReadFile(d.statePath())
+	if err != nil {
+		return false
 	}
-	return fileParts
+
+	var state downloadState
+	if err = json.Unmarshal(content, &state); err != nil {
+		return false
+	}
+	if d.contentLength <= 1 || state.ContentLength != d.contentLength || len(state.Parts) == 0 {
+		return false
+	}
+	if int64(len(state.Parts)) > 1 && d.parts == 1 {
+		// Server no longer supports partial downloads
+		return false
+	}
+
+	d.fileParts = state.Parts
+	d.parts = int64(len(state.Parts))
+	return true
 }
 
-// Check if the parts and the file does not already exist in the download directory
-// Return error if they are already present.
-func (d *HttpDownloader) VerifyDownloadDestination() error {
-	goSourcePath := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_DOWNLOAD_DIR, d.fileName)
-	if _, err := os.Stat(goSourcePath); !os.IsNotExist(err) {
+// Save the state of the parts so that the download can be resumed later
+func (d *HttpDownloader) SaveState() error {
+	content, err := json.Marshal(downloadState{
+		ContentLength: d.contentLength,
+		Parts:         d.fileParts,
+	})
+	if err != nil {
 		return err
 	}
+	return ioutil.WriteFile(d.statePath(), content, 0644)
+}
 
-	for _, part := range d.fileParts {
-		_, err := os.Stat(part.Path)
-		if !os.IsNotExist(err) {
-			return err
-		}
+// Move the download file to its final path once all the parts finished
+func (d *HttpDownloader) Finish() error {
+	goSourcePath := filepath.Join(d.dir, d.fileName)
+	if err := os.Rename(d.partialPath(), goSourcePath); err != nil {
+		return err
 	}
+	os.Remove(d.statePath())
 	return nil
 }
 
 // Clear/Remove already downloaded parts or file form downloads directory
 func (d *HttpDownloader) ClearPreviousDownload() error {
-	if err := utils.RemoveFilePartials(d.downloadUrl); err != nil {
+	if err := utils.RemoveFilePartials(d.dir, d.downloadUrl); err != nil {
 		return err
 	}
-	goSourcePath := filepath.Join(utils.GVM_ROOT_DIR, utils.GVM_DOWNLOAD_DIR, d.fileName)
+	goSourcePath := filepath.Join(d.dir, d.fileName)
 	if err := utils.RemoveAll([]string{goSourcePath}); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *HttpDownloader) Do(doneChan chan bool, fileChan chan string, errorChan chan error, interruptChan chan bool) {
+// Returns the size in bytes of each part of the download
+func (d *HttpDownloader) PartSizes() []int64 {
+	sizes := make([]int64, 0, len(d.fileParts))
+	for _, part := range d.fileParts {
+		sizes = append(sizes, d.partSize(part))
+	}
+	return sizes
+}
+
+func (d *HttpDownloader) partSize(part PartFile) int64 {
+	if part.RangeTo == d.contentLength {
+		return d.contentLength - part.RangeFrom
+	}
+	return part.RangeTo - part.RangeFrom + 1
+}
+
+// Download all the parts concurrently to the download file, the bytes written to
+// each part are reported on progressChan as they are written. A part which fails is
+// retried with exponential backoff resuming from the bytes already written to it, the
+// error is reported on errorChan only once the retries for the part are exhausted.
+// The parts stop once ctx is done.
+func (d *HttpDownloader) Do(ctx context.Context, doneChan chan bool, errorChan chan error, progressChan chan Progress) {
 	// Sync is for syncronization when implementing concurrency patterns
 	// WaitGroup wait for a collection of goroutines to finish
 	// The main goroutine calls Add to set the number of goroutines to wait for.
@@ -158,74 +352,170 @@ func (d *HttpDownloader) Do(doneChan chan bool, fileChan chan string, errorChan
 	// At the same time, Wait can be used to block until all goroutines have finished.
 	var ws sync.WaitGroup
 
-	for i, p := range d.fileParts {
+	f, err := d.openPartial()
+	if err != nil {
+		errorChan <- err
+		doneChan <- true
+		return
+	}
+	defer f.Close()
+
+	// Report the bytes written to the parts of a resumed download
+	for i, part := range d.fileParts {
+		if part.Written > 0 {
+			progressChan <- Progress{Part: int64(i), Bytes: part.Written}
+		}
+	}
+
+	for i := range d.fileParts {
 		ws.Add(1)
-		// GoRoutine for adding the parts to download
-		go func(d *HttpDownloader, partIndex int64, part PartFile) {
+		// GoRoutine for adding the parts to download, each routine only
+		// updates its own part.
+		go func(d *HttpDownloader, partIndex int64, part *PartFile) {
 			// Call done when the routine execution finish, to let wait group know about it.
 			defer ws.Done()
 
-			var ranges string
-			// Ranges Header for a part of the download
-			if part.RangeTo != d.contentLength {
-				ranges = fmt.Sprintf("bytes=%d-%d", part.RangeFrom, part.RangeTo)
-			} else {
-				ranges = fmt.Sprintf("bytes=%d-", part.RangeFrom) //get all
-			}
-
-			// Send the GET request
-			req, err := http.NewRequest("GET", d.downloadUrl, nil)
-			// If an error occurs push that error to error channel
-			if err != nil {
-				errorChan <- err
-				return
-			}
-
-			// Add range header in cases when part downloading is possible
-			req.Header.Add("Range", ranges)
-
-			// Make the above created request
-			res, err := client.Do(req)
-			if err != nil {
-				errorChan <- err
-				return
-			}
-			defer res.Body.Close()
-			// Write the contents of the downloads to the file
-			f, err := os.OpenFile(part.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0700)
+			for attempt := 0; ; attempt++ {
+				err := d.downloadPart(ctx, f, partIndex, part, progressChan)
+				if err == nil || ctx.Err() != nil {
+					return
+				}
 
-			defer f.Close()
-			if err != nil {
-				log.Errorf("%v", err)
-				errorChan <- err
-				return
-			}
+				if _, permanent := err.(*permanentError); permanent || attempt >= d.retries {
+					errorChan <- fmt.Errorf("Download of part %d failed after %d attempts : %v", partIndex, attempt+1, err)
+					return
+				}
 
-			// Make copy interruptable by copy 100 bytes each loop
-			current := int64(0)
-			var writer io.Writer
-			writer = io.MultiWriter(f)
-			for {
+				wait := backoff(attempt)
+				d.log.Warnf("Download of part %d failed : %v, retrying in %s", partIndex, err, wait)
 				select {
-				case <-interruptChan:
+				case <-ctx.Done():
 					return
-				default:
-					written, err := io.CopyN(writer, res.Body, 100)
-					current += written
-					if err != nil {
-						if err != io.EOF {
-							errorChan <- err
-						}
-						// File part download completes here
-						fileChan <- part.Path
-						return
-					}
+				case <-time.After(wait):
 				}
 			}
-		}(d, int64(i), p)
+		}(d, int64(i), &d.fileParts[i])
 	}
 
 	// Wait here until all the goroutines are done
 	ws.Wait()
 	doneChan <- true
 }
+
+// Open the download file, preallocating it to the size of the download when known
+func (d *HttpDownloader) openPartial() (*os.File, error) {
+	if err := utils.MkdirIfNotExist(d.dir); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(d.partialPath(), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if d.contentLength > 1 {
+		if info, err := f.Stat(); err == nil && info.Size() != d.contentLength {
+			if err = f.Truncate(d.contentLength); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	return f, nil
+}
+
+// Download a part at its offset in the download file, resuming from the bytes
+// already written for the part. Returns the error of ctx if it is done while copying.
+func (d *HttpDownloader) downloadPart(ctx context.Context, f *os.File, partIndex int64, part *PartFile, progressChan chan Progress) error {
+	if d.contentLength > 1 && part.Written >= d.partSize(*part) {
+		return nil
+	}
+
+	var ranges string
+	// Ranges Header for a part of the download
+	if part.RangeTo != d.contentLength {
+		ranges = fmt.Sprintf("bytes=%d-%d", part.RangeFrom+part.Written, part.RangeTo)
+	} else {
+		ranges = fmt.Sprintf("bytes=%d-", part.RangeFrom+part.Written) //get all
+	}
+
+	// Send the GET request
+	req, err := http.NewRequest("GET", d.downloadUrl, nil)
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	// Add range header in cases when part downloading is possible
+	req.Header.Add("Range", ranges)
+
+	// Make the above created request
+	res, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 500:
+		return fmt.Errorf("Server responded with %s", res.Status)
+	case res.StatusCode >= 400:
+		return &permanentError{fmt.Errorf("Server responded with %s", res.Status)}
+	}
+
+	if res.StatusCode != http.StatusPartialContent {
+		if len(d.fileParts) > 1 {
+			// The whole file would be written over the other parts
+			atomic.StoreInt32(&d.rangeIgnored, 1)
+			return &permanentError{errRangeIgnored}
+		}
+		if part.Written > 0 {
+			// Range was not honored, so the part is downloaded again from the start
+			progressChan <- Progress{Part: partIndex, Bytes: -part.Written}
+			part.Written = 0
+		}
+	}
+
+	writer := &offsetWriter{f: f, offset: part.RangeFrom + part.Written}
+	var body io.Reader = res.Body
+	if d.contentLength > 1 {
+		// Never write past the end of the part, even if the server sends more
+		body = io.LimitReader(res.Body, d.partSize(*part)-part.Written)
+	}
+	chunk := int64(copyChunkSize)
+	if d.limiter != nil {
+		chunk = d.limiter.chunkSize()
+	}
+
+	// Make copy interruptable by copying a chunk each loop
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			written, err := io.CopyN(writer, body, chunk)
+			part.Written += written
+			if written > 0 {
+				progressChan <- Progress{Part: partIndex, Bytes: written}
+			}
+			if d.limiter != nil && written > 0 {
+				if e := d.limiter.Wait(ctx, written); e != nil {
+					return e
+				}
+			}
+			if err == io.EOF {
+				if d.contentLength > 1 && part.Written < d.partSize(*part) {
+					return fmt.Errorf("Connection closed after %d of the %d bytes of the part", part.Written, d.partSize(*part))
+				}
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Reports if the server replied with the whole file to the request of a part, in
+// which case the file can only be downloaded with a single connection.
+func (d *HttpDownloader) RangeIgnored() bool {
+	return atomic.LoadInt32(&d.rangeIgnored) == 1
+}
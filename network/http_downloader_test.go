@@ -0,0 +1,107 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Returns a temporary downloads directory, removed when the returned function is called
+func tempDownloads(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gvm-downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func testContent() []byte {
+	content := make([]byte, 256*1024+7)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func TestDownloadParts(t *testing.T) {
+	dir, remove := tempDownloads(t)
+	defer remove()
+	content := testContent()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "go1.9.tar.gz", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	err := Download(context.Background(), server.URL+"/go1.9.tar.gz", DownloadOptions{Conn: 4, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, filepath.Join(dir, "go1.9.tar.gz"), content)
+}
+
+func TestDownloadRangeIgnored(t *testing.T) {
+	dir, remove := tempDownloads(t)
+	defer remove()
+	content := testContent()
+	// Advertises partial downloads but always replies with the whole file
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ACCEPT_RANGE_HEADER, "bytes")
+		w.Header().Set(CONTENT_LENGTH_HEADER, strconv.Itoa(len(content)))
+		if r.Method == "GET" {
+			w.Write(content)
+		}
+	}))
+	defer server.Close()
+
+	err := Download(context.Background(), server.URL+"/go1.9.tar.gz", DownloadOptions{Conn: 4, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, filepath.Join(dir, "go1.9.tar.gz"), content)
+}
+
+func TestDownloadPartTooLong(t *testing.T) {
+	dir, remove := tempDownloads(t)
+	defer remove()
+	content := testContent()
+	// Replies to the ranges with more than the range
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ACCEPT_RANGE_HEADER, "bytes")
+		w.Header().Set(CONTENT_LENGTH_HEADER, strconv.Itoa(len(content)))
+		if r.Method != "GET" {
+			return
+		}
+		var from int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &from); err != nil {
+			t.Errorf("invalid range %q", r.Header.Get("Range"))
+		}
+		w.Header().Del(CONTENT_LENGTH_HEADER)
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content[from:])
+		w.Write([]byte("trailing garbage"))
+	}))
+	defer server.Close()
+
+	err := Download(context.Background(), server.URL+"/go1.9.tar.gz", DownloadOptions{Conn: 4, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, filepath.Join(dir, "go1.9.tar.gz"), content)
+}
+
+func checkDownload(t *testing.T, path string, content []byte) {
+	downloaded, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("downloaded %d bytes which differ from the %d bytes served", len(downloaded), len(content))
+	}
+}
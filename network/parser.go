@@ -1,8 +1,10 @@
 package network
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"runtime"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/fristonio/gvm/utils"
@@ -13,21 +15,64 @@ type Release struct {
 	DownloadUrl string
 }
 
+// Reports if the release is a beta or a release candidate
+func (r Release) IsPrerelease() bool {
+	return utils.IsPrerelease(r.Name)
+}
+
 const (
-	TAGS_URL          = "https://go.googlesource.com/go/+refs"
-	BASE_DOWNLOAD_URL = "https://go.googlesource.com/go/+archive/%s.tar.gz"
+	TAGS_URL            = "https://go.googlesource.com/go/+refs"
+	BASE_DOWNLOAD_URL   = "https://go.googlesource.com/go/+archive/%s.tar.gz"
+	BINARY_DOWNLOAD_URL = "https://dl.google.com/go/%s"
 )
 
+// Returns the name of the source archive of the release
+func SourceArchiveName(releaseName string) string {
+	return releaseName + ".tar.gz"
+}
+
+// Returns the name of the official binary distribution archive of the release
+// for the current platform, like go1.9.linux-amd64.tar.gz
+func BinaryArchiveName(releaseName string) string {
+	ext := "tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("%s.%s-%s.%s", releaseName, runtime.GOOS, runtime.GOARCH, ext)
+}
+
+// ReleaseSource lists the releases of go available to install
+type ReleaseSource interface {
+	Releases(ctx context.Context) ([]Release, error)
+}
+
+// Source of the releases parsed from the tags of the go repository
+type tagsSource struct {
+	client *http.Client
+}
+
+// Returns the source listing the release tags of the go repository, which are
+// requested with the client.
+func NewTagsSource(client *http.Client) ReleaseSource {
+	return &tagsSource{client: client}
+}
+
 // Parses the available release of golang to install
-func ParseGoReleases(shouldLog bool) ([]Release, error) {
-	log.Info("Releases of go available for download are ")
+func (s *tagsSource) Releases(ctx context.Context) ([]Release, error) {
 	releases := make([]Release, 0)
 
-	res, err := http.Get(TAGS_URL)
-	defer res.Body.Close()
+	req, err := http.NewRequest("GET", TAGS_URL, nil)
 	if err != nil {
 		return releases, err
 	}
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return releases, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return releases, statusError(TAGS_URL, res)
+	}
 
 	doc, err := goquery.NewDocumentFromReader(res.Body)
 	if err != nil {
@@ -37,9 +82,6 @@ func ParseGoReleases(shouldLog bool) ([]Release, error) {
 		releaseName := s.Find("a").Text()
 
 		if utils.GOS_REGEXP.FindString(releaseName) != "" {
-			if shouldLog {
-				fmt.Println("    " + releaseName)
-			}
 			releases = append(releases, Release{
 				Name:        releaseName,
 				DownloadUrl: fmt.Sprintf(BASE_DOWNLOAD_URL, releaseName),
@@ -0,0 +1,139 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fristonio/gvm/logger"
+	"github.com/fristonio/gvm/utils"
+)
+
+// Minimum interval between two renders of the progress
+const (
+	TERMINAL_PROGRESS_INTERVAL = 200 * time.Millisecond
+	LOG_PROGRESS_INTERVAL      = 5 * time.Second
+)
+
+// Width of the progress bar in characters, shared between all the parts
+const progressBarWidth = 40
+
+// Progress of a part of the download, Bytes are the bytes written to the part
+// since its previous report.
+type Progress struct {
+	Part  int64
+	Bytes int64
+}
+
+// Displays the progress of a multi part download, as a progress bar when
+// stderr is a terminal and as periodic log lines otherwise. Nothing is displayed
+// when info messages are not logged.
+type progressDisplay struct {
+	enabled    bool
+	tty        bool
+	sizes      []int64
+	written    []int64
+	total      int64
+	downloaded int64
+	start      time.Time
+	lastRender time.Time
+	log        *logger.Logger
+}
+
+func newProgressDisplay(log *logger.Logger, sizes []int64) *progressDisplay {
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+
+	return &progressDisplay{
+		enabled: log.Enabled(logger.InfoLevel),
+		tty:     utils.IsTerminal(os.Stderr) && log.IsText(),
+		sizes:   sizes,
+		written: make([]int64, len(sizes)),
+		total:   total,
+		start:   time.Now(),
+		log:     log,
+	}
+}
+
+// Record the progress of a part and render it if the render interval has passed
+func (p *progressDisplay) Update(progress Progress) {
+	if progress.Part >= 0 && progress.Part < int64(len(p.written)) {
+		p.written[progress.Part] += progress.Bytes
+	}
+	p.downloaded += progress.Bytes
+	if !p.enabled {
+		return
+	}
+
+	interval := LOG_PROGRESS_INTERVAL
+	if p.tty {
+		interval = TERMINAL_PROGRESS_INTERVAL
+	}
+	if time.Since(p.lastRender) >= interval {
+		p.render()
+		p.lastRender = time.Now()
+	}
+}
+
+// Render the final state of the progress
+func (p *progressDisplay) Finish() {
+	if !p.enabled {
+		return
+	}
+	p.render()
+	if p.tty {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progressDisplay) render() {
+	elapsed := time.Since(p.start).Seconds()
+	var speed float64
+	if elapsed > 0 {
+		speed = float64(p.downloaded) / elapsed
+	}
+
+	// Total size is not known when the server did not send a Content-Length
+	known := p.total > 1
+	status := utils.MemoryBytesToString(p.downloaded)
+	if known {
+		status = fmt.Sprintf("%5.1f%% of %s", 100*float64(p.downloaded)/float64(p.total),
+			utils.MemoryBytesToString(p.total))
+	}
+	status += fmt.Sprintf(", %s/s", utils.MemoryBytesToString(int64(speed)))
+	if known && speed > 0 && p.downloaded < p.total {
+		eta := time.Duration(float64(p.total-p.downloaded)/speed) * time.Second
+		status += fmt.Sprintf(", ETA %s", eta)
+	}
+
+	if !p.tty {
+		p.log.WithFields(logger.Fields{"bytes": p.downloaded, "total": p.total}).Infof("Downloaded %s", status)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r%s %s\x1b[K", p.bar(known), status)
+}
+
+// Returns the progress bar with a segment for each part filled as per its progress
+func (p *progressDisplay) bar(known bool) string {
+	if !known || len(p.sizes) == 0 {
+		return ""
+	}
+
+	segmentWidth := progressBarWidth / len(p.sizes)
+	if segmentWidth < 1 {
+		segmentWidth = 1
+	}
+
+	segments := make([]string, 0, len(p.sizes))
+	for i, size := range p.sizes {
+		filled := segmentWidth
+		if size > 0 && p.written[i] < size {
+			filled = int(int64(segmentWidth) * p.written[i] / size)
+		}
+		segments = append(segments, strings.Repeat("=", filled)+strings.Repeat(" ", segmentWidth-filled))
+	}
+	return "[" + strings.Join(segments, "|") + "]"
+}
@@ -0,0 +1,77 @@
+package network
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Token bucket limiting the rate of bytes transferred, it is shared between all the
+// part routines of a download so the aggregate rate stays under the limit.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes added to the bucket per second
+	burst  float64 // maximum bytes in the bucket
+	tokens float64
+	last   time.Time
+}
+
+// Returns a rate limiter allowing bytesPerSec bytes per second, with bursts of
+// at most a second worth of bytes.
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(bytesPerSec),
+		burst:  float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// Takes n bytes from the bucket and returns the time to wait before they
+// can be transferred.
+func (l *RateLimiter) reserve(n int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Block until n bytes can be transferred, returns the error of ctx if it is done
+// while waiting.
+func (l *RateLimiter) Wait(ctx context.Context, n int64) error {
+	wait := l.reserve(n)
+	if wait == 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// Returns the bytes to copy in each iteration of the copy loop, small enough for
+// the limiter to keep the rate smooth.
+func (l *RateLimiter) chunkSize() int64 {
+	chunk := int64(l.rate / 10)
+	if chunk < 1024 {
+		chunk = 1024
+	}
+	if chunk > copyChunkSize {
+		chunk = copyChunkSize
+	}
+	return chunk
+}
@@ -0,0 +1,65 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := NewRateLimiter(1000)
+	if wait := l.reserve(1000); wait != 0 {
+		t.Errorf("a full bucket should not wait, waited %s", wait)
+	}
+
+	// The bucket is empty, 500 bytes take half a second at 1000 bytes per second
+	wait := l.reserve(500)
+	if wait < 450*time.Millisecond || wait > 500*time.Millisecond {
+		t.Errorf("expected to wait about 500ms, waited %s", wait)
+	}
+	// The reserved bytes are taken from the bucket so the waits add up
+	wait = l.reserve(500)
+	if wait < 950*time.Millisecond || wait > time.Second {
+		t.Errorf("expected to wait about 1s, waited %s", wait)
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := NewRateLimiter(1000)
+	l.last = l.last.Add(-time.Hour)
+	// The bucket never holds more than a second worth of bytes
+	if wait := l.reserve(2000); wait < 950*time.Millisecond {
+		t.Errorf("expected to wait about 1s after an idle hour, waited %s", wait)
+	}
+}
+
+func TestRateLimiterWaitCancelled(t *testing.T) {
+	l := NewRateLimiter(10)
+	l.reserve(10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := l.Wait(ctx, 100); err != context.Canceled {
+		t.Errorf("expected the error of the context, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("a cancelled wait should return right away")
+	}
+}
+
+func TestRateLimiterChunkSize(t *testing.T) {
+	tests := []struct {
+		rate     int64
+		expected int64
+	}{
+		{rate: 100, expected: 1024},
+		{rate: 100 * 1024, expected: 10 * 1024},
+		{rate: 1 << 40, expected: copyChunkSize},
+	}
+	for _, test := range tests {
+		if chunk := NewRateLimiter(test.rate).chunkSize(); chunk != test.expected {
+			t.Errorf("chunk size for %d bytes per second is %d, expected %d", test.rate, chunk, test.expected)
+		}
+	}
+}
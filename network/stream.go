@@ -0,0 +1,120 @@
+package network
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/fristonio/gvm/utils"
+)
+
+// Reader of the contents of a location streamed over a single connection, the
+// progress is displayed and the sha256 of the contents is computed while reading.
+type Stream struct {
+	ctx      context.Context
+	body     io.ReadCloser
+	hash     hash.Hash
+	progress *progressDisplay
+	limiter  *RateLimiter
+	// Error which stopped the reading of the contents, io.EOF excluded
+	err error
+}
+
+// Open the location to stream its contents instead of downloading it to the
+// downloads directory, the location can be any which is supported by a Fetcher.
+// Reading fails with the error of ctx once it is done.
+func OpenStream(ctx context.Context, location string, opts DownloadOptions) (*Stream, error) {
+	fetcher, err := NewFetcher(location)
+	if err != nil {
+		return nil, err
+	}
+	body, size, err := fetcher.Open(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	log := opts.log()
+	if size > 0 {
+		log.WithField("bytes", size).Infof("Download Size : %s", utils.MemoryBytesToString(size))
+	}
+
+	stream := &Stream{
+		ctx:      ctx,
+		body:     body,
+		hash:     sha256.New(),
+		progress: newProgressDisplay(log, []int64{size}),
+	}
+	if opts.LimitRate > 0 {
+		stream.limiter = NewRateLimiter(opts.LimitRate)
+	}
+	return stream, nil
+}
+
+func (s *Stream) Read(p []byte) (n int, err error) {
+	defer func() {
+		if err != nil && err != io.EOF && s.err == nil {
+			s.err = err
+		}
+	}()
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if s.limiter != nil && int64(len(p)) > s.limiter.chunkSize() {
+		p = p[:s.limiter.chunkSize()]
+	}
+
+	n, err = s.body.Read(p)
+	if n > 0 {
+		s.hash.Write(p[:n])
+		s.progress.Update(Progress{Part: 0, Bytes: int64(n)})
+		if s.limiter != nil {
+			if e := s.limiter.Wait(s.ctx, int64(n)); e != nil {
+				return n, e
+			}
+		}
+	}
+	return n, err
+}
+
+// Read the contents left, so that the checksum covers all of them
+func (s *Stream) Drain() error {
+	_, err := io.Copy(ioutil.Discard, s)
+	return err
+}
+
+// Returns the error which stopped the reading of the contents, nil if they were
+// read until their end or are still being read.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Returns the hex encoded sha256 of the contents read so far
+func (s *Stream) Sha256() string {
+	return hex.EncodeToString(s.hash.Sum(nil))
+}
+
+// Close the connection and render the final progress
+func (s *Stream) Close() error {
+	s.progress.Finish()
+	return s.body.Close()
+}
+
+// Send a GET request for the url, the contents are read over a single connection
+func (f *httpFetcher) Open(ctx context.Context, opts DownloadOptions) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequest("GET", f.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := opts.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, 0, utils.WrapError(utils.NETWORK_ERROR, err, "Error while requesting the resource")
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, 0, statusError(f.url, res)
+	}
+	return res.Body, res.ContentLength, nil
+}
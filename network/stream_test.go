@@ -0,0 +1,39 @@
+package network
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamErr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The connection is closed before the announced length is sent
+		if r.URL.Path == "/truncated" {
+			w.Header().Set("Content-Length", "100")
+		}
+		w.Write([]byte("contents"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		failed bool
+	}{
+		{path: "/complete"},
+		{path: "/truncated", failed: true},
+	}
+	for _, test := range tests {
+		stream, err := OpenStream(context.Background(), server.URL+test.path, DownloadOptions{Client: server.Client()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ioutil.ReadAll(stream)
+		stream.Close()
+		if (err != nil) != test.failed || stream.Err() != err {
+			t.Errorf("%s : read error %v, stream error %v", test.path, err, stream.Err())
+		}
+	}
+}
@@ -12,6 +12,10 @@ const (
 	GVM_PKGSET_NAME     string = "global"
 	GVM_PKGSET_DIRNAME  string = "pkgsets"
 	GVM_OVERLAY_DIRNAME string = "overlay"
+	GVM_PATCHES_DIRNAME string = "patches"
+	GVM_META_DIRNAME    string = "metadata"
+	GVM_DEFAULT_ENV     string = "default"
+	GVM_LOGS_DIRNAME    string = "logs"
 )
 
 var (
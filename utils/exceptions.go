@@ -1,12 +1,57 @@
 package utils
 
-// Check if the error is not nil printing the error and
-// panic after that.
-func FatalCheck(err error, errorMsg string, v ...interface{}) {
-	if err != nil {
-		if errorMsg == "" {
-			Log.Errorf(errorMsg, v...)
-		}
-		Log.Panic(err)
+import "fmt"
+
+// Kinds of errors reported by gvm, each kind is reported with its own exit code
+type ErrorKind int
+
+const (
+	GENERIC_ERROR ErrorKind = iota
+	// Invalid arguments or flags
+	USAGE_ERROR
+	// The version, release or file asked for does not exist
+	NOT_FOUND_ERROR
+	// A request failed or the download could not be completed
+	NETWORK_ERROR
+	// The downloaded archive is corrupted or can not be extracted safely
+	CHECKSUM_ERROR
+	// The compilation or the test suite of go failed
+	BUILD_ERROR
+	// The operation was aborted by the user
+	ABORTED_ERROR
+)
+
+// Error of a known kind wrapping the underlying error
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Returns an error of the kind with the message formatted in the manner of fmt.Errorf
+func NewError(kind ErrorKind, format string, v ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, v...)}
+}
+
+// Returns err prefixed with the message as an error of the kind, an error which
+// already has a kind keeps its kind. Returns nil if err is nil.
+func WrapError(kind ErrorKind, err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		kind = e.Kind
+	}
+	return &Error{Kind: kind, Err: fmt.Errorf("%s : %v", message, err)}
+}
+
+// Returns the kind of the error, GENERIC_ERROR for an error without a kind
+func KindOf(err error) ErrorKind {
+	if e, ok := err.(*Error); ok {
+		return e.Kind
 	}
+	return GENERIC_ERROR
 }
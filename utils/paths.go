@@ -0,0 +1,22 @@
+package utils
+
+import "path/filepath"
+
+// Root directory of the installations and the package set of their environments,
+// the packages locate the files of gvm from the paths they are given so that
+// several roots can be used at the same time.
+type Paths struct {
+	Root   string
+	Pkgset string
+}
+
+// Returns the paths of the root in GVM_ROOT_DIR and the package set in GVM_PKGSET_NAME
+func DefaultPaths() Paths {
+	return Paths{Root: GVM_ROOT_DIR, Pkgset: GVM_PKGSET_NAME}
+}
+
+// Returns the path of the elements joined under the root, like
+// Join(GVM_GOS_DIRNAME, "go1.9") for the installation of go1.9.
+func (p Paths) Join(elem ...string) string {
+	return filepath.Join(append([]string{p.Root}, elem...)...)
+}
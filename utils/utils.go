@@ -1,29 +1,70 @@
 package utils
 
 import (
-	"archive/tar"
-	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/fristonio/gvm/logger"
 )
 
-var Log *logger.Logger = logger.New(os.Stdout)
+var Log *logger.Logger = logger.Log
 
+// Pattern matching the name of a go release, including the beta and release
+// candidate tags like go1.21rc2
+const GOS_RELEASE_PATTERN string = `go\d+(?:\.\d+)*(?:(?:beta|rc)\d+)?`
+
+// Matches the name of a go release like go1.9
 var GOS_REGEXP *regexp.Regexp = getGosRegexp()
 
+// Matches the name of an installation, which is a go release optionally followed by
+// a variant name like go1.21.5@fips
+var GOS_NAME_REGEXP *regexp.Regexp = getGosNameRegexp()
+
+// Matches the suffix of the name of a beta or a release candidate of go
+var GOS_PRERELEASE_REGEXP *regexp.Regexp = regexp.MustCompile(`(?:beta|rc)\d+$`)
+
 func getGosRegexp() *regexp.Regexp {
-	gosRegexp, _ := regexp.Compile(`^go[\d\.]+$`)
+	gosRegexp, _ := regexp.Compile("^" + GOS_RELEASE_PATTERN + "$")
 	return gosRegexp
 }
 
+func getGosNameRegexp() *regexp.Regexp {
+	gosNameRegexp, _ := regexp.Compile("^(" + GOS_RELEASE_PATTERN + `)(?:@([\w\-]+))?$`)
+	return gosNameRegexp
+}
+
+// Checks if name is a valid name for a go installation, either a go release
+// or a named variant of it.
+func IsValidGoName(name string) bool {
+	return GOS_NAME_REGEXP.MatchString(name)
+}
+
+// Splits the name of an installation into the go release and the variant name,
+// variant is empty when name refers to the plain release.
+func SplitGoName(name string) (release string, variant string) {
+	matches := GOS_NAME_REGEXP.FindStringSubmatch(name)
+	if matches == nil {
+		return name, ""
+	}
+	return matches[1], matches[2]
+}
+
+// Checks if the go release is a beta or a release candidate
+func IsPrerelease(release string) bool {
+	return GOS_PRERELEASE_REGEXP.MatchString(release)
+}
+
 // Returns a string of IPv4 address from a list of IPs returned after lookup
 // of a hostname for IPs
 func GetIPv4StringArray(ips []net.IP) []string {
@@ -40,7 +81,6 @@ func GetIPv4StringArray(ips []net.IP) []string {
 // Takes byte count in integer format as input and returns a string describing download
 // size denoted by the bytecount
 func MemoryBytesToString(byteCount int64) string {
-	Log.Infof("Bytes : %v", byteCount)
 	var downloadSize string
 	if byteCount < 1024 {
 		downloadSize = fmt.Sprintf("%d Bytes", byteCount)
@@ -55,6 +95,52 @@ func MemoryBytesToString(byteCount int64) string {
 	return downloadSize
 }
 
+// Parses a size like 512K, 2M or 1.5G into bytes, the suffixes are powers of 1024
+// and a size without any suffix is in bytes.
+func ParseByteSize(sizeStr string) (int64, error) {
+	size := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(sizeStr)), "B")
+	multiplier := float64(1)
+	if size != "" {
+		switch size[len(size)-1] {
+		case 'K':
+			multiplier = 1024
+		case 'M':
+			multiplier = 1024 * 1024
+		case 'G':
+			multiplier = 1024 * 1024 * 1024
+		}
+		if multiplier > 1 {
+			size = size[:len(size)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(size, 64)
+	if err != nil || math.IsNaN(value) || value < 0 {
+		return 0, fmt.Errorf("Invalid size %q", sizeStr)
+	}
+	// Converting a size over the range of int64 is undefined, which includes infinity
+	bytes := value * multiplier
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("Size %q is too large", sizeStr)
+	}
+	return int64(bytes), nil
+}
+
+// Returns the hex encoded sha256 of the contents of the file
+func FileSha256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // Takes the name of the folder and create directory as according with
 // permissions 755
 func MkdirIfNotExist(folder string) error {
@@ -73,64 +159,62 @@ func CheckIfAlreadyExist(path string) bool {
 	return true
 }
 
-// Remove downloaded file partials corresponding to the url
-func RemoveFilePartials(url string) error {
+// Remove the partial download and its saved state corresponding to the url from
+// the downloads directory
+func RemoveFilePartials(downloadsDirectory string, url string) error {
 	file := filepath.Base(url)
-	downloadsDirectory := filepath.Join(GVM_ROOT_DIR, GVM_DOWNLOAD_DIR)
-	files, _ := filepath.Glob(downloadsDirectory + fmt.Sprintf("/%s.part*", file))
+	files, _ := filepath.Glob(downloadsDirectory + fmt.Sprintf("/%s.partial*", file))
 	err := RemoveAll(files)
 	return err
 }
 
+// Remove all the files, files which do not exist are ignored
 func RemoveAll(files []string) error {
 	for _, file := range files {
 		err := os.Remove(file)
-		if err != nil {
+		if err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
 	return nil
 }
 
-// Gets a list of files and joins them into a single file with name out
-func JoinFilePartials(files []string, out string) error {
-	// Sort the file names so that they are joined in the correct order
-	sort.Strings(files)
-	downloadsDirectory := filepath.Join(GVM_ROOT_DIR, GVM_DOWNLOAD_DIR)
-
-	Log.Info("Starting to Join file partials")
-
-	outf, err := os.OpenFile(filepath.Join(downloadsDirectory, out), os.O_CREATE|os.O_WRONLY, 0600)
-	defer outf.Close()
-	if err != nil {
-		return err
+// Print the installed gos with the variants grouped under their release, labels
+// contains an optional annotation to print along with each installation name.
+func PrintInstalledGos(gos []string, labels map[string]string) {
+	if len(gos) == 0 {
+		Log.Warn("No gos installed, to view a list of versions available use: go list-remote")
+		return
 	}
 
-	for _, f := range files {
-		if err = copy(f, outf); err != nil {
-			return err
+	releases := make([]string, 0)
+	installed := make(map[string]bool)
+	variants := make(map[string][]string)
+	for _, name := range gos {
+		release, variant := SplitGoName(name)
+		if _, ok := variants[release]; !ok {
+			releases = append(releases, release)
+			variants[release] = make([]string, 0)
+		}
+		if variant == "" {
+			installed[release] = true
+		} else {
+			variants[release] = append(variants[release], name)
 		}
 	}
-	return nil
-}
 
-func copy(from string, to io.Writer) error {
-	f, err := os.OpenFile(from, os.O_RDONLY, 0600)
-	defer f.Close()
-	if err != nil {
-		return err
-	}
-	io.Copy(to, f)
-	return nil
-}
+	sort.Strings(releases)
+	for i, release := range releases {
+		line := strconv.Itoa(i+1) + ". " + release
+		if !installed[release] {
+			line += " (variants only)"
+		}
+		fmt.Println(line + labels[release])
 
-func PrintInstalledGos(gos []string) {
-	if len(gos) == 0 {
-		Log.Warn("No gos installed, to view a list of versions available use: go list-remote")
-		return
-	}
-	for i, f := range gos {
-		fmt.Println(strconv.Itoa(i+1) + ". " + f)
+		sort.Strings(variants[release])
+		for _, name := range variants[release] {
+			fmt.Println("    " + name + labels[name])
+		}
 	}
 }
 
@@ -159,78 +243,54 @@ func CheckIfDirExist(dirString string) error {
 	return nil
 }
 
-// Untar source to destination
-func UntarToDestination(source string, destination string) error {
-	if _, err := os.Stat(destination); err == nil {
-		os.RemoveAll(destination)
-	}
-
-	if e := MkdirIfNotExist(destination); e != nil {
-		return e
-	}
+// Spinner shows an activity indicator on the terminal while some long running
+// operation is going on, nothing is shown when stderr is not a terminal.
+type Spinner struct {
+	message string
+	stop    chan bool
+	done    chan bool
+}
 
-	file, e := os.Open(source)
-	defer file.Close()
-	if e != nil {
-		return e
+// Returns a new spinner showing the message along with the indicator
+func NewSpinner(message string) *Spinner {
+	return &Spinner{
+		message: message,
+		stop:    make(chan bool),
+		done:    make(chan bool),
 	}
+}
 
-	gzr, err := gzip.NewReader(file)
-	defer gzr.Close()
-	if err != nil {
-		return err
-	}
-
-	tr := tar.NewReader(gzr)
-
-	for {
-		header, err := tr.Next()
-
-		switch {
-
-		// if no more files are found return
-		case err == io.EOF:
-			return nil
-
-		// return any other error
-		case err != nil:
-			return err
-
-		// if the header is nil, just skip it (not sure how this happens)
-		case header == nil:
-			continue
+// Start showing the spinner in background
+func (s *Spinner) Start() {
+	go func() {
+		defer close(s.done)
+		if !IsTerminal(os.Stderr) || !Log.IsText() {
+			<-s.stop
+			return
 		}
 
-		// the target location where the dir/file should be created
-		target := filepath.Join(destination, header.Name)
-
-		// the following switch could also be done using fi.Mode(), not sure if there
-		// a benefit of using one vs. the other.
-		// fi := header.FileInfo()
-
-		// check the file type
-		switch header.Typeflag {
-
-		// if its a dir and it doesn't exist create it
-		case tar.TypeDir:
-			if _, err := os.Stat(target); err != nil {
-				if err := os.MkdirAll(target, 0755); err != nil {
-					return err
-				}
+		frames := `|/-\`
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(os.Stderr, "\r%c %s", frames[i%len(frames)], s.message)
+			select {
+			case <-s.stop:
+				fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", len(s.message)+2))
+				return
+			case <-ticker.C:
 			}
+		}
+	}()
+}
 
-		// if it's a file create it
-		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
-				return err
-			}
+// Stop the spinner and clear it from the terminal
+func (s *Spinner) Stop() {
+	close(s.stop)
+	<-s.done
+}
 
-			// copy over contents
-			if _, err := io.Copy(f, tr); err != nil {
-				return err
-			}
-			f.Close()
-		}
-	}
+// Checks if the file is a terminal
+func IsTerminal(f *os.File) bool {
+	return logger.IsTerminal(f)
 }
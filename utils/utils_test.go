@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		size     string
+		expected int64
+		invalid  bool
+	}{
+		{size: "0", expected: 0},
+		{size: "512", expected: 512},
+		{size: "512B", expected: 512},
+		{size: "2K", expected: 2048},
+		{size: "2k", expected: 2048},
+		{size: "2M", expected: 2 * 1024 * 1024},
+		{size: "1.5G", expected: 1536 * 1024 * 1024},
+		{size: " 4GB ", expected: 4 * 1024 * 1024 * 1024},
+		{size: "", invalid: true},
+		{size: "M", invalid: true},
+		{size: "-1K", invalid: true},
+		{size: "abc", invalid: true},
+		{size: "inf", invalid: true},
+		{size: "+Inf", invalid: true},
+		{size: "NaN", invalid: true},
+		{size: "1e30", invalid: true},
+		{size: "9223372036854775807", invalid: true},
+		{size: "8589934592G", invalid: true},
+	}
+
+	for _, test := range tests {
+		size, err := ParseByteSize(test.size)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("ParseByteSize(%q) = %d, expected an error", test.size, size)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseByteSize(%q) failed : %v", test.size, err)
+		} else if size != test.expected {
+			t.Errorf("ParseByteSize(%q) = %d, expected %d", test.size, size, test.expected)
+		}
+	}
+}
+
+func TestIsValidGoName(t *testing.T) {
+	tests := map[string]bool{
+		"go1":           true,
+		"go1.9":         true,
+		"go1.21.5":      true,
+		"go1.21.5@fips": true,
+		"go1.21rc2":     true,
+		"go1.22beta1@x": true,
+		"go1..2":        false,
+		"go1.21rc":      false,
+		"go1.21.5@":     false,
+		"1.21":          false,
+		"go1.21/..":     false,
+	}
+	for name, valid := range tests {
+		if IsValidGoName(name) != valid {
+			t.Errorf("IsValidGoName(%q) = %v, expected %v", name, !valid, valid)
+		}
+	}
+}
+
+func TestPrintInstalledGos(t *testing.T) {
+	gos := []string{"go1.9@race", "go1.10", "go1.9", "go1.11@fips", "go1.9@debug"}
+	labels := map[string]string{"go1.9": " (default)", "go1.9@race": " (patched)"}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	PrintInstalledGos(gos, labels)
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `1. go1.10
+2. go1.11 (variants only)
+    go1.11@fips
+3. go1.9 (default)
+    go1.9@debug
+    go1.9@race (patched)
+`
+	if string(out) != expected {
+		t.Errorf("printed :\n%s\nexpected :\n%s", out, expected)
+	}
+}